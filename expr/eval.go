@@ -0,0 +1,403 @@
+package expr
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+func logical(col int, left, right evaluator, or bool) evaluator {
+	return func(record interface{}) (interface{}, error) {
+		l, err := boolean(col, left, record)
+		if err != nil || l == or {
+			return l, err
+		}
+		return boolean(col, right, record)
+	}
+}
+
+func boolean(col int, operand evaluator, record interface{}) (bool, error) {
+	value, err := operand(record)
+	if err != nil || value == nil {
+		return false, err
+	}
+	b, ok := value.(bool)
+	if !ok {
+		return false, evalError(col, "expected a boolean, got %s", kindOf(value))
+	}
+	return b, nil
+}
+
+func unary(col int, op string, operand evaluator) evaluator {
+	if op == "!" {
+		return func(record interface{}) (interface{}, error) {
+			b, err := boolean(col, operand, record)
+			if err != nil {
+				return false, err
+			}
+			return !b, nil
+		}
+	}
+	return func(record interface{}) (interface{}, error) {
+		value, err := operand(record)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		if i, ok := toInt(value); ok {
+			return -i, nil
+		}
+		if f, ok := toFloat(value); ok {
+			return -f, nil
+		}
+		return nil, evalError(col, "cannot negate %s", kindOf(value))
+	}
+}
+
+func binary(col int, op string, left, right evaluator) evaluator {
+	return func(record interface{}) (interface{}, error) {
+		l, err := left(record)
+		if err != nil {
+			return nil, err
+		}
+		r, err := right(record)
+		if err != nil {
+			return nil, err
+		}
+		switch op {
+		case "==":
+			return equal(l, r), nil
+		case "!=":
+			return !equal(l, r), nil
+		case "<", "<=", ">", ">=":
+			// Null, like a missing field, is neither smaller nor greater than any value
+			if l == nil || r == nil {
+				return false, nil
+			}
+			c, err := compare(col, l, r)
+			if err != nil {
+				return nil, err
+			}
+			switch op {
+			case "<":
+				return c < 0, nil
+			case "<=":
+				return c <= 0, nil
+			case ">":
+				return c > 0, nil
+			}
+			return c >= 0, nil
+		case "in":
+			if r == nil {
+				return false, nil
+			}
+			return contains(col, r, l)
+		}
+		// Arithmetic on null gives null, so that records missing a field are filtered out by comparisons
+		if l == nil || r == nil {
+			return nil, nil
+		}
+		return arithmetic(col, op, l, r)
+	}
+}
+
+// arithmetic keeps integers as int, except for "/" which always divides as floats
+func arithmetic(col int, op string, l, r interface{}) (interface{}, error) {
+	if ls, ok := l.(string); ok && op == "+" {
+		if rs, ok := r.(string); ok {
+			return ls + rs, nil
+		}
+	}
+	li, lInt := toInt(l)
+	ri, rInt := toInt(r)
+	if lInt && rInt && op != "/" {
+		switch op {
+		case "+":
+			return li + ri, nil
+		case "-":
+			return li - ri, nil
+		case "*":
+			return li * ri, nil
+		}
+		if ri == 0 {
+			return nil, evalError(col, "modulo by zero")
+		}
+		return li % ri, nil
+	}
+	lf, lNum := toFloat(l)
+	rf, rNum := toFloat(r)
+	if !lNum || !rNum {
+		return nil, evalError(col, "cannot apply %q to %s and %s", op, kindOf(l), kindOf(r))
+	}
+	switch op {
+	case "+":
+		return lf + rf, nil
+	case "-":
+		return lf - rf, nil
+	case "*":
+		return lf * rf, nil
+	case "/":
+		if rf == 0 {
+			return nil, evalError(col, "division by zero")
+		}
+		return lf / rf, nil
+	}
+	return math.Mod(lf, rf), nil
+}
+
+// equal compares numbers by value whatever their types, and other values deeply
+func equal(l, r interface{}) bool {
+	if lf, ok := toFloat(l); ok {
+		rf, ok := toFloat(r)
+		return ok && lf == rf
+	}
+	return reflect.DeepEqual(l, r)
+}
+
+// compare orders two numbers, two strings or two booleans, false coming first
+func compare(col int, l, r interface{}) (int, error) {
+	if lf, ok := toFloat(l); ok {
+		if rf, ok := toFloat(r); ok {
+			switch {
+			case lf < rf:
+				return -1, nil
+			case lf > rf:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+	lv, rv := reflect.ValueOf(l), reflect.ValueOf(r)
+	if l != nil && r != nil && lv.Kind() == rv.Kind() {
+		switch lv.Kind() {
+		case reflect.String:
+			return strings.Compare(lv.String(), rv.String()), nil
+		case reflect.Bool:
+			lb, rb := lv.Bool(), rv.Bool()
+			switch {
+			case lb == rb:
+				return 0, nil
+			case rb:
+				return -1, nil
+			}
+			return 1, nil
+		}
+	}
+	return 0, evalError(col, "cannot compare %s with %s", kindOf(l), kindOf(r))
+}
+
+// contains tells whether a list holds an item, a map holds a key, or a string holds a substring
+func contains(col int, collection, item interface{}) (interface{}, error) {
+	v := reflect.ValueOf(collection)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		for i := 0; i < v.Len(); i++ {
+			if equal(v.Index(i).Interface(), item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case reflect.Map:
+		if !hashable(item) {
+			return nil, evalError(col, "cannot look for %s in an object, as it is not a valid key", kindOf(item))
+		}
+		key, ok := mapKey(v, item)
+		return ok && v.MapIndex(key).IsValid(), nil
+	case reflect.String:
+		if s, ok := item.(string); ok {
+			return strings.Contains(v.String(), s), nil
+		}
+	}
+	return nil, evalError(col, "cannot look for %s in %s", kindOf(item), kindOf(collection))
+}
+
+// field reads a field of a map with string keys, or of a struct, missing map keys and fields of null being null
+func field(col int, operand evaluator, name string) evaluator {
+	return func(record interface{}) (interface{}, error) {
+		value, err := operand(record)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		if m, ok := value.(map[string]interface{}); ok {
+			return m[name], nil
+		}
+		v := reflect.ValueOf(value)
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil, nil
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Map:
+			if v.Type().Key().Kind() == reflect.String {
+				return valueOf(v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))), nil
+			}
+		case reflect.Struct:
+			if i := fieldIndex(v.Type(), name); i != nil {
+				return valueOf(v.FieldByIndex(i)), nil
+			}
+			return nil, evalError(col, "%v has no field %q", v.Type(), name)
+		}
+		return nil, evalError(col, "cannot read field %q of %s", name, kindOf(value))
+	}
+}
+
+// index reads an item of a list, null when out of range, or a value of a map
+func index(col int, operand, key evaluator) evaluator {
+	return func(record interface{}) (interface{}, error) {
+		value, err := operand(record)
+		if err != nil || value == nil {
+			return nil, err
+		}
+		k, err := key(record)
+		if err != nil {
+			return nil, err
+		}
+		v := reflect.ValueOf(value)
+		for v.Kind() == reflect.Ptr || v.Kind() == reflect.Interface {
+			if v.IsNil() {
+				return nil, nil
+			}
+			v = v.Elem()
+		}
+		switch v.Kind() {
+		case reflect.Slice, reflect.Array:
+			i, ok := toInt(k)
+			if !ok {
+				return nil, evalError(col, "cannot index a list with %s", kindOf(k))
+			}
+			if i < 0 || i >= v.Len() {
+				return nil, nil
+			}
+			return v.Index(i).Interface(), nil
+		case reflect.Map:
+			mk, ok := mapKey(v, k)
+			if !ok {
+				return nil, evalError(col, "cannot index %v with %s", v.Type(), kindOf(k))
+			}
+			return valueOf(v.MapIndex(mk)), nil
+		case reflect.Struct:
+			if name, ok := k.(string); ok {
+				return field(col, constant(v.Interface()), name)(record)
+			}
+		}
+		return nil, evalError(col, "cannot index %s with %s", kindOf(value), kindOf(k))
+	}
+}
+
+// hashable tells whether a value can be looked up in a map without panicking
+func hashable(key interface{}) bool {
+	return key == nil || reflect.TypeOf(key).Comparable()
+}
+
+func mapKey(m reflect.Value, key interface{}) (reflect.Value, bool) {
+	if key == nil || !hashable(key) {
+		return reflect.Value{}, false
+	}
+	k := reflect.ValueOf(key)
+	keyType := m.Type().Key()
+	if k.Type().AssignableTo(keyType) {
+		return k, true
+	}
+	if i, ok := toInt(key); ok && keyType.Kind() >= reflect.Int && keyType.Kind() <= reflect.Uint64 {
+		return reflect.ValueOf(i).Convert(keyType), true
+	}
+	if k.Kind() == reflect.String && keyType.Kind() == reflect.String {
+		return k.Convert(keyType), true
+	}
+	return reflect.Value{}, false
+}
+
+func valueOf(v reflect.Value) interface{} {
+	if !v.IsValid() {
+		return nil
+	}
+	return v.Interface()
+}
+
+type fieldKey struct {
+	t    reflect.Type
+	name string
+}
+
+// fields caches the index of struct fields by name
+var fields sync.Map
+
+// fieldIndex finds an exported field by its JSON name, then by its name, then by its name ignoring case
+func fieldIndex(t reflect.Type, name string) []int {
+	key := fieldKey{t: t, name: name}
+	if i, ok := fields.Load(key); ok {
+		return i.([]int)
+	}
+	var byTag, byName, byFold []int
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		tag := strings.Split(f.Tag.Get("json"), ",")[0]
+		switch {
+		case tag == name && byTag == nil:
+			byTag = f.Index
+		case f.Name == name && byName == nil:
+			byName = f.Index
+		case strings.EqualFold(f.Name, name) && byFold == nil:
+			byFold = f.Index
+		}
+	}
+	index := byTag
+	if index == nil {
+		index = byName
+	}
+	if index == nil {
+		index = byFold
+	}
+	fields.Store(key, index)
+	return index
+}
+
+func toInt(value interface{}) (int, bool) {
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return int(v.Int()), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return int(v.Uint()), true
+	}
+	return 0, false
+}
+
+func toFloat(value interface{}) (float64, bool) {
+	if i, ok := toInt(value); ok {
+		return float64(i), true
+	}
+	v := reflect.ValueOf(value)
+	switch v.Kind() {
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), true
+	}
+	return 0, false
+}
+
+// kindOf names the kind of a value in error messages
+func kindOf(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	switch reflect.ValueOf(value).Kind() {
+	case reflect.Bool:
+		return "boolean"
+	case reflect.String:
+		return "string"
+	case reflect.Slice, reflect.Array:
+		return "list"
+	case reflect.Map, reflect.Struct:
+		return "object"
+	}
+	if _, ok := toFloat(value); ok {
+		return "number"
+	}
+	return fmt.Sprintf("%T", value)
+}
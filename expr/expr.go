@@ -0,0 +1,259 @@
+// Package expr compiles small expressions over records into functions streams can filter, map and sort with
+//
+// Records are maps with string keys or structs, read through fields like user.id or items[0]
+// Struct fields are found by their JSON name, their name, or their name ignoring case, and _ stands for the record itself
+// Expressions support literals, including lists like ["ok", "warn"] and objects like {user: user.id, ms: latency_ms},
+// arithmetic with + - * / %, comparisons with == != < <= > >= and in, and logic with && || !
+//
+// Missing fields are null, which arithmetic passes on, which is neither smaller nor greater than any value,
+// and which counts as false in logic
+// So a predicate like latency_ms > 200 is false for records without latency_ms, instead of failing
+package expr
+
+import (
+	"fmt"
+	"strings"
+	"unicode/utf8"
+)
+
+// SyntaxError describes an expression which cannot be compiled
+type SyntaxError struct {
+	// Column is the 1-based position of the error in the expression, counted in characters
+	Column  int
+	Message string
+}
+
+func (e *SyntaxError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Message)
+}
+
+// EvalError describes an expression which cannot be evaluated for a record, like one comparing a string with a number
+type EvalError struct {
+	// Column is the 1-based position of the failing operator in the expression, counted in characters
+	Column  int
+	Message string
+}
+
+func (e *EvalError) Error() string {
+	return fmt.Sprintf("column %d: %s", e.Column, e.Message)
+}
+
+func evalError(col int, format string, args ...interface{}) error {
+	return &EvalError{Column: col, Message: fmt.Sprintf(format, args...)}
+}
+
+// Expression is a compiled expression, safe for concurrent use
+type Expression struct {
+	src  string
+	eval evaluator
+}
+
+// Compile parses an expression
+//
+// @param src	Expression to compile
+// @return		The compiled expression, or a *SyntaxError locating what cannot be parsed
+func Compile(src string) (*Expression, error) {
+	eval, err := compile(src)
+	if err != nil {
+		return nil, err
+	}
+	return &Expression{src: src, eval: eval}, nil
+}
+
+// MustCompile is like Compile but panics if the expression cannot be parsed
+func MustCompile(src string) *Expression {
+	e, err := Compile(src)
+	if err != nil {
+		panic(fmt.Sprintf("Invalid expression %q: %v", src, err))
+	}
+	return e
+}
+
+func compile(src string) (evaluator, error) {
+	tokens, err := tokenize(src)
+	if err != nil {
+		return nil, err
+	}
+	p := &parser{src: src, tokens: tokens}
+	eval, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != eof {
+		return nil, p.errorAt(t, fmt.Sprintf("unexpected %s", describeToken(t)))
+	}
+	return eval, nil
+}
+
+// String returns the source of the expression
+func (e *Expression) String() string {
+	return e.src
+}
+
+// Eval computes the value of the expression for a record
+//
+// @param record	Map or struct to read fields from
+// @return			The value, or an *EvalError if an operator cannot be applied
+func (e *Expression) Eval(record interface{}) (interface{}, error) {
+	return e.eval(record)
+}
+
+// Predicate returns a function usable by Filter, keeping records for which the expression is true
+// Null counts as false, but the function panics with an *EvalError if the expression cannot be evaluated or is not a boolean
+// Such a panic in a go routine of a parallel stream cannot be recovered and ends the process,
+// so use PredicateWith when records may not all fit the expression
+func (e *Expression) Predicate() func(interface{}) bool {
+	return e.PredicateWith(func(err error) {
+		panic(err)
+	})
+}
+
+// PredicateWith returns a function usable by Filter, keeping records for which the expression is true
+// Records for which the expression cannot be evaluated or is not a boolean are reported to onError and left out
+//
+// @param onError	Handler of *EvalError, called concurrently by parallel streams
+// @return			A predicate over records
+func (e *Expression) PredicateWith(onError func(err error)) func(interface{}) bool {
+	return func(record interface{}) bool {
+		value, err := e.eval(record)
+		if err == nil {
+			if b, ok := value.(bool); ok || value == nil {
+				return b
+			}
+			err = evalError(1, "expected a boolean, got %s", kindOf(value))
+		}
+		onError(err)
+		return false
+	}
+}
+
+// Mapper returns a function usable by Map
+// The function panics with an *EvalError if the expression cannot be evaluated, see Predicate for parallel streams
+func (e *Expression) Mapper() func(interface{}) interface{} {
+	return e.MapperWith(func(err error) {
+		panic(err)
+	})
+}
+
+// MapperWith returns a function usable by Map
+// Records for which the expression cannot be evaluated are reported to onError and mapped to nil
+//
+// @param onError	Handler of *EvalError, called concurrently by parallel streams
+// @return			A mapper over records
+func (e *Expression) MapperWith(onError func(err error)) func(interface{}) interface{} {
+	return func(record interface{}) interface{} {
+		value, err := e.eval(record)
+		if err != nil {
+			onError(err)
+			return nil
+		}
+		return value
+	}
+}
+
+type sortKey struct {
+	eval       evaluator
+	column     int
+	descending bool
+}
+
+// CompileLess parses a comma-separated list of sort keys, each one an expression optionally followed by asc or desc,
+// like "status, latency_ms desc"
+// Keys are compared in turn, numbers, strings and booleans being supported, and null coming before any other value
+// The returned function is usable by Sorted, and panics with an *EvalError if keys of two records cannot be compared
+//
+// @param src	Sort keys to compile
+// @return		A function telling whether a record comes before another, or a *SyntaxError
+func CompileLess(src string) (func(a, b interface{}) bool, error) {
+	var keys []sortKey
+	start := 0
+	for _, part := range splitKeys(src) {
+		text := strings.TrimRight(part, " \t\r\n")
+		descending := false
+		for _, suffix := range []string{"desc", "asc"} {
+			trimmed := strings.TrimSuffix(text, suffix)
+			if trimmed != text && trimmed != strings.TrimRight(trimmed, " \t\r\n") {
+				text, descending = trimmed, suffix == "desc"
+				break
+			}
+		}
+		// Pad the key so that errors report columns within the whole source
+		eval, err := compile(strings.Repeat(" ", utf8.RuneCountInString(src[:start])) + text)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, sortKey{
+			eval:       eval,
+			column:     column(src, start+len(text)-len(strings.TrimLeft(text, " \t\r\n"))),
+			descending: descending,
+		})
+		start += len(part) + 1
+	}
+	return func(a, b interface{}) bool {
+		for _, key := range keys {
+			c, err := compareKeys(key, a, b)
+			if err != nil {
+				panic(err)
+			}
+			if c != 0 {
+				return c < 0
+			}
+		}
+		return false
+	}, nil
+}
+
+func compareKeys(key sortKey, a, b interface{}) (int, error) {
+	ka, err := key.eval(a)
+	if err != nil {
+		return 0, err
+	}
+	kb, err := key.eval(b)
+	if err != nil {
+		return 0, err
+	}
+	var c int
+	switch {
+	case ka == nil && kb == nil:
+	case ka == nil:
+		c = -1
+	case kb == nil:
+		c = 1
+	default:
+		if c, err = compare(key.column, ka, kb); err != nil {
+			return 0, err
+		}
+	}
+	if key.descending {
+		c = -c
+	}
+	return c, nil
+}
+
+// splitKeys splits sort keys on top-level commas, leaving those within brackets and strings
+func splitKeys(src string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case quote != 0:
+			if c == '\\' {
+				i++
+			} else if c == quote {
+				quote = 0
+			}
+		case c == '"' || c == '\'':
+			quote = c
+		case c == '(' || c == '[' || c == '{':
+			depth++
+		case c == ')' || c == ']' || c == '}':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, src[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, src[start:])
+}
@@ -0,0 +1,108 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+type tokenKind int
+
+const (
+	eof tokenKind = iota
+	identifier
+	number
+	text
+	punctuation
+)
+
+type token struct {
+	kind tokenKind
+	// value is the operator or identifier itself, the unquoted content of a string, or the digits of a number
+	value string
+	// pos is the byte offset of the token in the source
+	pos int
+}
+
+// operators are sorted so that longer ones are matched first
+var operators = []string{"&&", "||", "==", "!=", "<=", ">=", "!", "<", ">", "+", "-", "*", "/", "%", "(", ")", "[", "]", "{", "}", ".", ",", ":"}
+
+// column converts a byte offset into a 1-based column counted in characters
+func column(src string, pos int) int {
+	return utf8.RuneCountInString(src[:pos]) + 1
+}
+
+func tokenize(src string) ([]token, error) {
+	var tokens []token
+	pos := 0
+	for pos < len(src) {
+		r, size := utf8.DecodeRuneInString(src[pos:])
+		switch {
+		case unicode.IsSpace(r):
+			pos += size
+		case r == '_' || unicode.IsLetter(r):
+			end := pos
+			for end < len(src) {
+				r, size := utf8.DecodeRuneInString(src[end:])
+				if r != '_' && !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+					break
+				}
+				end += size
+			}
+			tokens = append(tokens, token{kind: identifier, value: src[pos:end], pos: pos})
+			pos = end
+		case r >= '0' && r <= '9':
+			end := pos
+			for end < len(src) && (src[end] >= '0' && src[end] <= '9' || src[end] == '.') {
+				end++
+			}
+			if _, err := strconv.ParseFloat(src[pos:end], 64); err != nil {
+				return nil, &SyntaxError{Column: column(src, pos), Message: fmt.Sprintf("invalid number %q", src[pos:end])}
+			}
+			tokens = append(tokens, token{kind: number, value: src[pos:end], pos: pos})
+			pos = end
+		case r == '"' || r == '\'':
+			value, end, err := scanString(src, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: text, value: value, pos: pos})
+			pos = end
+		default:
+			matched := false
+			for _, op := range operators {
+				if strings.HasPrefix(src[pos:], op) {
+					tokens = append(tokens, token{kind: punctuation, value: op, pos: pos})
+					pos += len(op)
+					matched = true
+					break
+				}
+			}
+			if !matched {
+				return nil, &SyntaxError{Column: column(src, pos), Message: fmt.Sprintf("unexpected character %q", r)}
+			}
+		}
+	}
+	return append(tokens, token{kind: eof, pos: len(src)}), nil
+}
+
+// scanString reads a string quoted with ' or ", which supports the escapes of Go strings
+func scanString(src string, start int) (string, int, error) {
+	quote := src[start]
+	var b strings.Builder
+	pos := start + 1
+	for pos < len(src) {
+		if src[pos] == quote {
+			return b.String(), pos + 1, nil
+		}
+		value, _, tail, err := strconv.UnquoteChar(src[pos:], quote)
+		if err != nil {
+			return "", 0, &SyntaxError{Column: column(src, pos), Message: "invalid escape in string"}
+		}
+		b.WriteRune(value)
+		pos = len(src) - len(tail)
+	}
+	return "", 0, &SyntaxError{Column: column(src, start), Message: "unterminated string"}
+}
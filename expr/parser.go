@@ -0,0 +1,342 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// evaluator computes the value of a compiled expression node for a record
+type evaluator func(record interface{}) (interface{}, error)
+
+type parser struct {
+	src    string
+	tokens []token
+	next   int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.next]
+}
+
+func (p *parser) advance() token {
+	t := p.tokens[p.next]
+	if t.kind != eof {
+		p.next++
+	}
+	return t
+}
+
+// accept consumes the next token if it is one of the given operators or keywords
+func (p *parser) accept(values ...string) (token, bool) {
+	t := p.peek()
+	if t.kind != punctuation && t.kind != identifier {
+		return t, false
+	}
+	for _, value := range values {
+		if t.value == value {
+			return p.advance(), true
+		}
+	}
+	return t, false
+}
+
+func (p *parser) expect(value string) error {
+	if _, ok := p.accept(value); !ok {
+		return p.errorAt(p.peek(), fmt.Sprintf("expected %q, found %s", value, describeToken(p.peek())))
+	}
+	return nil
+}
+
+func (p *parser) errorAt(t token, message string) error {
+	return &SyntaxError{Column: column(p.src, t.pos), Message: message}
+}
+
+func describeToken(t token) string {
+	switch t.kind {
+	case eof:
+		return "end of expression"
+	case text:
+		return strconv.Quote(t.value)
+	}
+	return fmt.Sprintf("%q", t.value)
+}
+
+// Grammar, from the lowest precedence to the highest:
+//
+//	or       = and { "||" and }
+//	and      = compare { "&&" compare }
+//	compare  = additive [ ( "==" | "!=" | "<" | "<=" | ">" | ">=" | "in" ) additive ]
+//	additive = multiply { ( "+" | "-" ) multiply }
+//	multiply = unary { ( "*" | "/" | "%" ) unary }
+//	unary    = ( "!" | "-" ) unary | postfix
+//	postfix  = primary { "." identifier | "[" or "]" }
+//	primary  = number | string | "true" | "false" | "null" | identifier | "(" or ")" | list | object
+//	list     = "[" [ or { "," or } ] "]"
+//	object   = "{" [ key ":" or { "," key ":" or } ] "}"
+func (p *parser) parseOr() (evaluator, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("||")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = logical(p.column(op), left, right, true)
+	}
+}
+
+func (p *parser) parseAnd() (evaluator, error) {
+	left, err := p.parseCompare()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("&&")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseCompare()
+		if err != nil {
+			return nil, err
+		}
+		left = logical(p.column(op), left, right, false)
+	}
+}
+
+func (p *parser) parseCompare() (evaluator, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	op, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "in")
+	if !ok {
+		return left, nil
+	}
+	right, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+	if next, ok := p.accept("==", "!=", "<", "<=", ">", ">=", "in"); ok {
+		return nil, p.errorAt(next, fmt.Sprintf("comparisons cannot be chained, use && between %q and %q", op.value, next.value))
+	}
+	return binary(p.column(op), op.value, left, right), nil
+}
+
+func (p *parser) parseAdditive() (evaluator, error) {
+	left, err := p.parseMultiply()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("+", "-")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseMultiply()
+		if err != nil {
+			return nil, err
+		}
+		left = binary(p.column(op), op.value, left, right)
+	}
+}
+
+func (p *parser) parseMultiply() (evaluator, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		op, ok := p.accept("*", "/", "%")
+		if !ok {
+			return left, nil
+		}
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = binary(p.column(op), op.value, left, right)
+	}
+}
+
+func (p *parser) parseUnary() (evaluator, error) {
+	op, ok := p.accept("!", "-")
+	if !ok {
+		return p.parsePostfix()
+	}
+	operand, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+	return unary(p.column(op), op.value, operand), nil
+}
+
+func (p *parser) parsePostfix() (evaluator, error) {
+	value, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+	for {
+		if op, ok := p.accept("."); ok {
+			name := p.advance()
+			if name.kind != identifier {
+				return nil, p.errorAt(name, fmt.Sprintf("expected a field name after \".\", found %s", describeToken(name)))
+			}
+			value = field(p.column(op), value, name.value)
+			continue
+		}
+		if op, ok := p.accept("["); ok {
+			key, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect("]"); err != nil {
+				return nil, err
+			}
+			value = index(p.column(op), value, key)
+			continue
+		}
+		return value, nil
+	}
+}
+
+func (p *parser) parsePrimary() (evaluator, error) {
+	t := p.advance()
+	switch t.kind {
+	case number:
+		value, err := parseNumber(t.value)
+		if err != nil {
+			return nil, p.errorAt(t, fmt.Sprintf("invalid number %q", t.value))
+		}
+		return constant(value), nil
+	case text:
+		return constant(t.value), nil
+	case identifier:
+		switch t.value {
+		case "true":
+			return constant(true), nil
+		case "false":
+			return constant(false), nil
+		case "null":
+			return constant(nil), nil
+		case "in":
+			return nil, p.errorAt(t, "unexpected keyword \"in\"")
+		case "_":
+			return func(record interface{}) (interface{}, error) {
+				return record, nil
+			}, nil
+		}
+		return field(p.column(t), func(record interface{}) (interface{}, error) {
+			return record, nil
+		}, t.value), nil
+	case punctuation:
+		switch t.value {
+		case "(":
+			value, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			return value, p.expect(")")
+		case "[":
+			return p.parseList()
+		case "{":
+			return p.parseObject()
+		}
+	}
+	return nil, p.errorAt(t, fmt.Sprintf("unexpected %s", describeToken(t)))
+}
+
+func (p *parser) parseList() (evaluator, error) {
+	var items []evaluator
+	if _, ok := p.accept("]"); !ok {
+		for {
+			item, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			items = append(items, item)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+	}
+	return func(record interface{}) (interface{}, error) {
+		result := make([]interface{}, len(items))
+		for i, item := range items {
+			value, err := item(record)
+			if err != nil {
+				return nil, err
+			}
+			result[i] = value
+		}
+		return result, nil
+	}, nil
+}
+
+func (p *parser) parseObject() (evaluator, error) {
+	var keys []string
+	var values []evaluator
+	if _, ok := p.accept("}"); !ok {
+		for {
+			key := p.advance()
+			if key.kind != identifier && key.kind != text {
+				return nil, p.errorAt(key, fmt.Sprintf("expected a key, found %s", describeToken(key)))
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, err
+			}
+			value, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			keys = append(keys, key.value)
+			values = append(values, value)
+			if _, ok := p.accept(","); !ok {
+				break
+			}
+		}
+		if err := p.expect("}"); err != nil {
+			return nil, err
+		}
+	}
+	return func(record interface{}) (interface{}, error) {
+		result := make(map[string]interface{}, len(keys))
+		for i, key := range keys {
+			value, err := values[i](record)
+			if err != nil {
+				return nil, err
+			}
+			result[key] = value
+		}
+		return result, nil
+	}, nil
+}
+
+func (p *parser) column(t token) int {
+	return column(p.src, t.pos)
+}
+
+// parseNumber returns an int for integer literals and a float64 otherwise
+func parseNumber(s string) (interface{}, error) {
+	if !strings.Contains(s, ".") {
+		if i, err := strconv.Atoi(s); err == nil {
+			return i, nil
+		}
+	}
+	return strconv.ParseFloat(s, 64)
+}
+
+func constant(value interface{}) evaluator {
+	return func(interface{}) (interface{}, error) {
+		return value, nil
+	}
+}
@@ -0,0 +1,155 @@
+// Package optional provides a typed counterpart of util.Optional
+package optional
+
+import (
+	"fmt"
+	"reflect"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/dynastywind/go-stream/util"
+)
+
+// Optional holds a value of type T or nothing
+// Presence is tracked by a flag rather than by comparing with nil, so a present nil pointer is representable
+// Its zero value is empty
+type Optional[T any] struct {
+	value   T
+	present bool
+}
+
+// Of returns a present Optional holding the given value, which may be nil
+func Of[T any](value T) Optional[T] {
+	return Optional[T]{
+		value:   value,
+		present: true,
+	}
+}
+
+// Empty returns an empty Optional
+func Empty[T any]() Optional[T] {
+	return Optional[T]{}
+}
+
+// FromOptional converts an untyped Optional, such as one returned by FindFirst, Max or ReduceOptional
+// It panics if the value held is not of type T
+func FromOptional[T any](opt *util.Optional) Optional[T] {
+	if !opt.IsPresent() {
+		return Empty[T]()
+	}
+	value, ok := opt.Get().(T)
+	if !ok {
+		panic(fmt.Sprintf("Optional value of type %T is not of type %v", opt.Get(), reflect.TypeOf((*T)(nil)).Elem()))
+	}
+	return Of(value)
+}
+
+// Map applies a mapper onto the value if present
+func Map[T any, R any](opt Optional[T], mapper func(T) R) Optional[R] {
+	if !opt.present {
+		return Empty[R]()
+	}
+	return Of(mapper(opt.value))
+}
+
+// FlatMap applies a mapper generating an Optional onto the value if present
+func FlatMap[T any, R any](opt Optional[T], mapper func(T) Optional[R]) Optional[R] {
+	if !opt.present {
+		return Empty[R]()
+	}
+	return mapper(opt.value)
+}
+
+func (opt Optional[T]) IsPresent() bool {
+	return opt.present
+}
+
+func (opt Optional[T]) Get() T {
+	if !opt.present {
+		panic("No such element")
+	}
+	return opt.value
+}
+
+func (opt Optional[T]) OrElse(or T) T {
+	if !opt.present {
+		return or
+	}
+	return opt.value
+}
+
+func (opt Optional[T]) OrElseGet(getter func() T) T {
+	if !opt.present {
+		return getter()
+	}
+	return opt.value
+}
+
+// OrElseErr returns the value if present, the given error otherwise
+func (opt Optional[T]) OrElseErr(err error) (T, error) {
+	if !opt.present {
+		var zero T
+		return zero, err
+	}
+	return opt.value, nil
+}
+
+// Or returns this Optional if present, the one supplied otherwise
+func (opt Optional[T]) Or(supplier func() Optional[T]) Optional[T] {
+	if !opt.present {
+		return supplier()
+	}
+	return opt
+}
+
+func (opt Optional[T]) Filter(filter func(T) bool) Optional[T] {
+	if !opt.present || filter(opt.value) {
+		return opt
+	}
+	return Empty[T]()
+}
+
+func (opt Optional[T]) IfPresent(consumer func(T)) {
+	if opt.present {
+		consumer(opt.value)
+	}
+}
+
+func (opt Optional[T]) IfPresentOrElse(consumer func(T), otherwise func()) {
+	if opt.present {
+		consumer(opt.value)
+	} else {
+		otherwise()
+	}
+}
+
+// Equal returns true if both Optionals are empty, or both present with deeply equal values
+func (opt Optional[T]) Equal(other Optional[T]) bool {
+	if opt.present != other.present {
+		return false
+	}
+	return !opt.present || reflect.DeepEqual(opt.value, other.value)
+}
+
+// Stream returns a sequential stream of the value if present, an empty one otherwise
+func (opt Optional[T]) Stream() stream.Stream {
+	if !opt.present {
+		return stream.Of()
+	}
+	return stream.Of(opt.value)
+}
+
+// ToOptional converts to an untyped Optional
+// As those decide presence by comparing with nil, a present nil interface value becomes empty
+func (opt Optional[T]) ToOptional() *util.Optional {
+	if !opt.present {
+		return util.OfEmpty()
+	}
+	return util.OfNillable(opt.value)
+}
+
+func (opt Optional[T]) String() string {
+	if !opt.present {
+		return ""
+	}
+	return fmt.Sprint(opt.value)
+}
@@ -0,0 +1,99 @@
+package stream
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// CollectError describes a data item which cannot be stored into a typed collection
+type CollectError struct {
+	// Index is the 0-based position of the data item in the stream
+	Index int
+	// Role tells which part of the data item failed, such as "element", "key" or "value"
+	Role   string
+	Item   interface{}
+	Target reflect.Type
+}
+
+func (e *CollectError) Error() string {
+	if e.Item == nil {
+		return fmt.Sprintf("%s %d: nil cannot be collected as %v", e.Role, e.Index, e.Target)
+	}
+	return fmt.Sprintf("%s %d: value of type %T cannot be collected as %v", e.Role, e.Index, e.Item, e.Target)
+}
+
+// CollectInto appends data items of a stream to a typed slice, growing it at most once
+// Nothing is appended if any data item is not of type T, or is nil while T cannot be nil
+//
+// @param s			Stream to collect
+// @param target	Slice to append data items to
+// @return			A *CollectError naming the first data item which is not of type T, nil otherwise
+func CollectInto[T any](s Stream, target *[]T) error {
+	arr := s.ToArray()
+	result := *target
+	if cap(result)-len(result) < len(arr) {
+		grown := make([]T, len(result), len(result)+len(arr))
+		copy(grown, result)
+		result = grown
+	}
+	convert := converterOf[T]("element")
+	for i, item := range arr {
+		value, err := convert(i, item)
+		if err != nil {
+			return err
+		}
+		result = append(result, value)
+	}
+	*target = result
+	return nil
+}
+
+// CollectIntoMap puts data items of a stream into a typed map, allocating it with enough room if nil
+// Nothing is put if any key or value is not of the map's type, or is nil while this type cannot be nil
+//
+// @param s				Stream to collect
+// @param target		Map to put data items into
+// @param keyMapper		Function to map data item to map key
+// @param valueMapper	Function to map data item to map value
+// @return				A *CollectError naming the first data item whose key or value is mistyped, nil otherwise
+func CollectIntoMap[K comparable, V any](s Stream, target *map[K]V, keyMapper func(interface{}) interface{}, valueMapper func(interface{}) interface{}) error {
+	arr := s.ToArray()
+	keys := make([]K, len(arr))
+	values := make([]V, len(arr))
+	convertKey := converterOf[K]("key")
+	convertValue := converterOf[V]("value")
+	for i, item := range arr {
+		var err error
+		if keys[i], err = convertKey(i, keyMapper(item)); err != nil {
+			return err
+		}
+		if values[i], err = convertValue(i, valueMapper(item)); err != nil {
+			return err
+		}
+	}
+	if *target == nil {
+		*target = make(map[K]V, len(arr))
+	}
+	for i, key := range keys {
+		(*target)[key] = values[i]
+	}
+	return nil
+}
+
+// converterOf returns a function asserting items to type T
+// Reflection is only used once here, to find out whether nil is a valid T
+func converterOf[T any](role string) func(int, interface{}) (T, error) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	nillable := false
+	switch t.Kind() {
+	case reflect.Chan, reflect.Func, reflect.Interface, reflect.Map, reflect.Ptr, reflect.Slice:
+		nillable = true
+	}
+	return func(index int, item interface{}) (T, error) {
+		value, ok := item.(T)
+		if !ok && !(item == nil && nillable) {
+			return value, &CollectError{Index: index, Role: role, Item: item, Target: t}
+		}
+		return value, nil
+	}
+}
@@ -0,0 +1,147 @@
+package stream
+
+import (
+	"reflect"
+
+	"github.com/dynastywind/go-stream/util"
+)
+
+// Collector describes a mutable reduction, which a parallel stream runs over each routine's chunk of items before merging the partial results
+type Collector interface {
+	// Supply returns an empty container
+	Supply() interface{}
+
+	// Accumulate adds a data item into a container and returns the updated container
+	Accumulate(container interface{}, item interface{}) interface{}
+
+	// Combine merges the container of a chunk into the one of the previous chunk and returns the merged container
+	Combine(container interface{}, other interface{}) interface{}
+
+	// Finish turns the final container into the collected result
+	Finish(container interface{}) interface{}
+}
+
+type sketchCollector struct {
+	supply     func() interface{}
+	accumulate func(interface{}, interface{})
+	combine    func(interface{}, interface{})
+	finish     func(interface{}) interface{}
+}
+
+func (c *sketchCollector) Supply() interface{} {
+	return c.supply()
+}
+
+func (c *sketchCollector) Accumulate(container interface{}, item interface{}) interface{} {
+	c.accumulate(container, item)
+	return container
+}
+
+func (c *sketchCollector) Combine(container interface{}, other interface{}) interface{} {
+	c.combine(container, other)
+	return container
+}
+
+func (c *sketchCollector) Finish(container interface{}) interface{} {
+	return c.finish(container)
+}
+
+// CountDistinctApprox estimates the number of unique items with a HyperLogLog sketch of 2^precision bytes
+// The collected result is an uint64, within about 1.04 / sqrt(2^precision) of the exact count
+//
+// @param hash		Function to generate data item's identity
+// @param precision	Number of bits used to index the sketch registers, between 4 and 18
+// @return			A collector estimating the number of unique items
+func CountDistinctApprox(hash func(interface{}) string, precision uint8) Collector {
+	// Fail on an invalid precision when building the pipeline rather than when evaluating it
+	util.NewHyperLogLog(precision)
+	return &sketchCollector{
+		supply: func() interface{} {
+			return util.NewHyperLogLog(precision)
+		},
+		accumulate: func(container interface{}, item interface{}) {
+			container.(*util.HyperLogLog).Add(hash(item))
+		},
+		combine: func(container interface{}, other interface{}) {
+			container.(*util.HyperLogLog).Merge(other.(*util.HyperLogLog))
+		},
+		finish: func(container interface{}) interface{} {
+			return container.(*util.HyperLogLog).Count()
+		},
+	}
+}
+
+// Quantiles estimates quantiles of numeric data items with a KLL sketch holding a few hundred values
+// The collected result is a []float64 with one estimate per requested quantile, NaN for an empty stream
+// Data items should be of any integer or float kind
+//
+// @param qs	Quantiles to estimate, between 0 and 1
+// @return		A collector estimating the quantiles of data items
+func Quantiles(qs ...float64) Collector {
+	return &sketchCollector{
+		supply: func() interface{} {
+			return util.NewQuantileSketch(200)
+		},
+		accumulate: func(container interface{}, item interface{}) {
+			container.(*util.QuantileSketch).Add(toFloat(item))
+		},
+		combine: func(container interface{}, other interface{}) {
+			container.(*util.QuantileSketch).Merge(other.(*util.QuantileSketch))
+		},
+		finish: func(container interface{}) interface{} {
+			result := make([]float64, len(qs))
+			for i, q := range qs {
+				result[i] = container.(*util.QuantileSketch).Quantile(q)
+			}
+			return result
+		},
+	}
+}
+
+// FrequentItems estimates the k most frequent data items with a Space-Saving summary of the given number of counters
+// Counts overestimate the real number of occurrences by at most n/counters in n items, and rare items churn through
+// the counters, so use several times k counters, like 10k, for the k most frequent items to be told apart from the others
+// The collected result is a []util.ItemCount of at most k items from the most to the least frequent
+// Data items should be comparable
+//
+// @param k			Number of items to return
+// @param counters	Number of counters of the summary, at least k
+// @return			A collector estimating the most frequent data items
+func FrequentItems(k int, counters int) Collector {
+	// Fail on invalid sizes when building the pipeline rather than when evaluating it
+	util.NewSpaceSaving(counters)
+	if k < 1 || k > counters {
+		panic("Number of items should be greater than 0 and at most the number of counters")
+	}
+	return &sketchCollector{
+		supply: func() interface{} {
+			return util.NewSpaceSaving(counters)
+		},
+		accumulate: func(container interface{}, item interface{}) {
+			container.(*util.SpaceSaving).Add(item)
+		},
+		combine: func(container interface{}, other interface{}) {
+			container.(*util.SpaceSaving).Merge(other.(*util.SpaceSaving))
+		},
+		finish: func(container interface{}) interface{} {
+			top := container.(*util.SpaceSaving).Top()
+			if len(top) > k {
+				top = top[:k]
+			}
+			return top
+		},
+	}
+}
+
+func toFloat(item interface{}) float64 {
+	value := reflect.ValueOf(item)
+	switch value.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(value.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return float64(value.Uint())
+	case reflect.Float32, reflect.Float64:
+		return value.Float()
+	}
+	panic("Cannot compute quantiles of non-numeric item " + value.String())
+}
@@ -25,3 +25,30 @@ func ToTypedArray(arr []interface{}, t reflect.Type) reflect.Value {
 	}
 	return result
 }
+
+// Collect accumulates every chunk of items into its own container, then merges containers in chunk order
+//
+// @param supplier		Function to create an empty container
+// @param accumulator	Function to add an item into a container, returning the updated container
+// @param combiner		Function to merge a container into the one of the previous chunk
+func Collect(arr []interface{}, num int, supplier func() interface{}, accumulator func(interface{}, interface{}) interface{}, combiner func(interface{}, interface{}) interface{}) interface{} {
+	if num < 1 {
+		num = 1
+	}
+	if num > len(arr) && len(arr) > 0 {
+		num = len(arr)
+	}
+	containers := make([]interface{}, num)
+	forEachChunk(len(arr), num, func(chunk, lo, hi int) {
+		container := supplier()
+		for _, item := range arr[lo:hi] {
+			container = accumulator(container, item)
+		}
+		containers[chunk] = container
+	})
+	result := containers[0]
+	for _, container := range containers[1:] {
+		result = combiner(result, container)
+	}
+	return result
+}
@@ -0,0 +1,95 @@
+package operation
+
+import (
+	"container/list"
+	"time"
+
+	"github.com/dynastywind/go-stream/util"
+)
+
+// Distinct keeps the first occurrence of every key, in encounter order
+// Keys should be comparable, as they are used as map keys
+func Distinct(arr []interface{}, key func(interface{}) interface{}) []interface{} {
+	return DistinctInParallel(arr, 1, key)
+}
+
+// DistinctInParallel does the same thing as Distinct, keys being computed in parallel
+func DistinctInParallel(arr []interface{}, num int, key func(interface{}) interface{}) []interface{} {
+	seen := make(map[interface{}]struct{}, len(arr))
+	return keepFirst(arr, keysOf(arr, num, key), func(key interface{}) bool {
+		if _, ok := seen[key]; ok {
+			return true
+		}
+		seen[key] = struct{}{}
+		return false
+	})
+}
+
+// DistinctApprox keeps the first occurrence of every hash in a Bloom filter of bounded size
+// Some unique items may be dropped as false positives, at the given rate once expectedN items are seen
+func DistinctApprox(arr []interface{}, num int, hash func(interface{}) string, expectedN int, fpRate float64) []interface{} {
+	filter := util.NewBloomFilter(expectedN, fpRate)
+	return keepFirst(arr, hashesOf(arr, num, hash), func(key interface{}) bool {
+		return filter.TestAndAdd(key.(string))
+	})
+}
+
+// DistinctWithin drops items whose hash is among the last window ones remembered
+func DistinctWithin(arr []interface{}, num int, hash func(interface{}) string, window int) []interface{} {
+	seen := util.NewLRUSet(window)
+	return keepFirst(arr, hashesOf(arr, num, hash), seen.TestAndAdd)
+}
+
+type timedKey struct {
+	key  string
+	time time.Time
+}
+
+// DistinctWithinTime drops items whose hash was already seen less than window before their timestamp
+// Timestamps are expected to be roughly increasing, keys being forgotten in encounter order
+func DistinctWithinTime(arr []interface{}, num int, hash func(interface{}) string, timestamp func(interface{}) time.Time, window time.Duration) []interface{} {
+	times := keysOf(arr, num, func(item interface{}) interface{} {
+		return timestamp(item)
+	})
+	order := list.New()
+	lastSeen := make(map[string]*list.Element)
+	i := 0
+	return keepFirst(arr, hashesOf(arr, num, hash), func(key interface{}) bool {
+		now := times[i].(time.Time)
+		i++
+		for front := order.Front(); front != nil && !now.Before(front.Value.(timedKey).time.Add(window)); front = order.Front() {
+			delete(lastSeen, order.Remove(front).(timedKey).key)
+		}
+		if element, ok := lastSeen[key.(string)]; ok {
+			element.Value = timedKey{key: key.(string), time: now}
+			order.MoveToBack(element)
+			return true
+		}
+		lastSeen[key.(string)] = order.PushBack(timedKey{key: key.(string), time: now})
+		return false
+	})
+}
+
+// keepFirst keeps items in encounter order unless seen reports their key as a duplicate
+func keepFirst(arr []interface{}, keys []interface{}, seen func(interface{}) bool) []interface{} {
+	result := make([]interface{}, 0)
+	for i, item := range arr {
+		if !seen(keys[i]) {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+func keysOf(arr []interface{}, num int, key func(interface{}) interface{}) []interface{} {
+	if num <= 1 {
+		return DoMap(arr, key)
+	}
+	return DoMapInParallel(arr, num, key, true)
+}
+
+func hashesOf(arr []interface{}, num int, hash func(interface{}) string) []interface{} {
+	return keysOf(arr, num, func(item interface{}) interface{} {
+		return hash(item)
+	})
+}
@@ -1,9 +1,5 @@
 package operation
 
-import (
-	"github.com/Workiva/go-datastructures/queue"
-)
-
 func Filter(arr []interface{}, filter func(interface{}) bool) []interface{} {
 	var result []interface{}
 	for _, item := range arr {
@@ -20,15 +16,12 @@ type FilterResultWrapper struct {
 	data   interface{}
 }
 
-func (wrapper FilterResultWrapper) Compare(other queue.Item) int {
-	return wrapper.index - other.(FilterResultWrapper).index
-}
-
 func FilterInParallel(arr []interface{}, num int, filter func(interface{}) bool, ordered bool) []interface{} {
 	length := len(arr)
 	ch := make(chan FilterResultWrapper, length)
 	result := make([]interface{}, 0)
-	pq := queue.NewPriorityQueue(length, true)
+	// Items are kept by index rather than in a priority queue, which would need them to be hashable
+	kept := make([]bool, length)
 	for i, item := range arr {
 		go func(index int, data interface{}) {
 			ch <- FilterResultWrapper{
@@ -42,7 +35,7 @@ func FilterInParallel(arr []interface{}, num int, filter func(interface{}) bool,
 				wrapper := <-ch
 				if wrapper.result {
 					if ordered {
-						pq.Put(wrapper)
+						kept[wrapper.index] = true
 					} else {
 						result = append(result, wrapper.data)
 					}
@@ -51,12 +44,10 @@ func FilterInParallel(arr []interface{}, num int, filter func(interface{}) bool,
 		}
 	}
 	if ordered {
-		items, err := pq.Get(pq.Len())
-		if err != nil {
-			panic("Should succeed with priority queue get")
-		}
-		for _, wrapper := range items {
-			result = append(result, wrapper.(FilterResultWrapper).data)
+		for i, item := range arr {
+			if kept[i] {
+				result = append(result, item)
+			}
 		}
 	}
 	return result
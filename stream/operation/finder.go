@@ -6,12 +6,12 @@ import (
 	"github.com/dynastywind/go-stream/util"
 )
 
-func FindAny(arr []interface{}) *util.Optional {
+func FindAny(arr []interface{}, random *rand.Rand) *util.Optional {
 	length := len(arr)
 	if length == 0 {
 		return util.OfEmpty()
 	}
-	return util.OfNillable(arr[rand.Intn(length)])
+	return util.OfNillable(arr[intn(random, length)])
 }
 
 func FindFirst(arr []interface{}) *util.Optional {
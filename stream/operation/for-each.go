@@ -1,5 +1,7 @@
 package operation
 
+import "sync"
+
 func ForEachParallel(arr []interface{}, num int, consumer func(interface{})) {
 	length := len(arr)
 	ch := make(chan int, length)
@@ -15,3 +17,20 @@ func ForEachParallel(arr []interface{}, num int, consumer func(interface{})) {
 		}
 	}
 }
+
+// forEachChunk splits length items into num chunks of nearly equal size, and handles each in its own go routine
+func forEachChunk(length int, num int, handler func(chunk, lo, hi int)) {
+	if num <= 1 {
+		handler(0, 0, length)
+		return
+	}
+	var wg sync.WaitGroup
+	wg.Add(num)
+	for i := 0; i < num; i++ {
+		go func(chunk int) {
+			defer wg.Done()
+			handler(chunk, length*chunk/num, length*(chunk+1)/num)
+		}(i)
+	}
+	wg.Wait()
+}
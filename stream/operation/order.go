@@ -1,16 +1,195 @@
 package operation
 
-import "github.com/dynastywind/go-stream/util"
+import (
+	"container/heap"
 
+	"github.com/dynastywind/go-stream/util"
+)
+
+// MaxOrMin returns the first greatest or smallest item in a single pass
 func MaxOrMin(arr []interface{}, less func(interface{}, interface{}) bool, max bool) *util.Optional {
-	sorted := util.MergeSort(arr, less)
-	length := len(sorted)
-	if length == 0 {
+	return MaxOrMinInParallel(arr, 1, less, max)
+}
+
+// MaxOrMinInParallel does the same thing as MaxOrMin, each go routine scanning a chunk of items
+func MaxOrMinInParallel(arr []interface{}, num int, less func(interface{}, interface{}) bool, max bool) *util.Optional {
+	if len(arr) == 0 {
 		return util.OfEmpty()
 	}
 	if max {
-		return util.OfNillable(sorted[length-1])
-	} else {
-		return util.OfNillable(sorted[0])
+		less = reversed(less)
+	}
+	found := make([]int, num)
+	forEachChunk(len(arr), num, func(chunk, lo, hi int) {
+		found[chunk] = smallestIndex(arr, lo, hi, less)
+	})
+	best := -1
+	for _, index := range found {
+		if index >= 0 && (best < 0 || less(arr[index], arr[best])) {
+			best = index
+		}
+	}
+	return util.OfNillable(arr[best])
+}
+
+// MinMax returns the first smallest and the first greatest items in a single pass
+func MinMax(arr []interface{}, less func(interface{}, interface{}) bool) (*util.Optional, *util.Optional) {
+	return MinMaxInParallel(arr, 1, less)
+}
+
+// MinMaxInParallel does the same thing as MinMax, each go routine scanning a chunk of items
+func MinMaxInParallel(arr []interface{}, num int, less func(interface{}, interface{}) bool) (*util.Optional, *util.Optional) {
+	if len(arr) == 0 {
+		return util.OfEmpty(), util.OfEmpty()
+	}
+	mins := make([]int, num)
+	maxes := make([]int, num)
+	forEachChunk(len(arr), num, func(chunk, lo, hi int) {
+		mins[chunk], maxes[chunk] = -1, -1
+		for i := lo; i < hi; i++ {
+			if mins[chunk] < 0 || less(arr[i], arr[mins[chunk]]) {
+				mins[chunk] = i
+			}
+			if maxes[chunk] < 0 || less(arr[maxes[chunk]], arr[i]) {
+				maxes[chunk] = i
+			}
+		}
+	})
+	min, max := -1, -1
+	for chunk := range mins {
+		if mins[chunk] >= 0 && (min < 0 || less(arr[mins[chunk]], arr[min])) {
+			min = mins[chunk]
+		}
+		if maxes[chunk] >= 0 && (max < 0 || less(arr[max], arr[maxes[chunk]])) {
+			max = maxes[chunk]
+		}
+	}
+	return util.OfNillable(arr[min]), util.OfNillable(arr[max])
+}
+
+func smallestIndex(arr []interface{}, lo, hi int, less func(interface{}, interface{}) bool) int {
+	if lo >= hi {
+		return -1
+	}
+	best := lo
+	for i := lo + 1; i < hi; i++ {
+		if less(arr[i], arr[best]) {
+			best = i
+		}
+	}
+	return best
+}
+
+type rankedItem struct {
+	index int
+	data  interface{}
+}
+
+// boundedHeap keeps the k smallest items seen, with its greatest one on top
+// Equal items are ranked by their index so that the earliest ones are kept
+type boundedHeap struct {
+	items []rankedItem
+	less  func(interface{}, interface{}) bool
+}
+
+func (h *boundedHeap) before(a, b rankedItem) bool {
+	if h.less(a.data, b.data) {
+		return true
+	}
+	return !h.less(b.data, a.data) && a.index < b.index
+}
+
+func (h *boundedHeap) Len() int {
+	return len(h.items)
+}
+
+func (h *boundedHeap) Less(i, j int) bool {
+	return h.before(h.items[j], h.items[i])
+}
+
+func (h *boundedHeap) Swap(i, j int) {
+	h.items[i], h.items[j] = h.items[j], h.items[i]
+}
+
+func (h *boundedHeap) Push(x interface{}) {
+	h.items = append(h.items, x.(rankedItem))
+}
+
+func (h *boundedHeap) Pop() interface{} {
+	last := h.items[len(h.items)-1]
+	h.items = h.items[:len(h.items)-1]
+	return last
+}
+
+func smallest(items []rankedItem, k int, less func(interface{}, interface{}) bool) []rankedItem {
+	// The heap never holds more than every item, whatever k is
+	if k > len(items) {
+		k = len(items)
+	}
+	h := &boundedHeap{
+		items: make([]rankedItem, 0, k),
+		less:  less,
+	}
+	for _, item := range items {
+		if h.Len() < k {
+			heap.Push(h, item)
+		} else if h.before(item, h.items[0]) {
+			h.items[0] = item
+			heap.Fix(h, 0)
+		}
+	}
+	result := make([]rankedItem, h.Len())
+	for i := len(result) - 1; i >= 0; i-- {
+		result[i] = heap.Pop(h).(rankedItem)
+	}
+	return result
+}
+
+// Smallest returns the k smallest items in ascending order, in O(n log k)
+func Smallest(arr []interface{}, k int, less func(interface{}, interface{}) bool) []interface{} {
+	return SmallestInParallel(arr, 1, k, less)
+}
+
+// SmallestInParallel does the same thing as Smallest, keeping a bounded heap per go routine and merging them at last
+func SmallestInParallel(arr []interface{}, num int, k int, less func(interface{}, interface{}) bool) []interface{} {
+	if k <= 0 {
+		return []interface{}{}
+	}
+	length := len(arr)
+	items := make([]rankedItem, length)
+	for i, item := range arr {
+		items[i] = rankedItem{index: i, data: item}
+	}
+	if num > 1 && length > k {
+		chunks := make([][]rankedItem, num)
+		forEachChunk(length, num, func(chunk, lo, hi int) {
+			chunks[chunk] = smallest(items[lo:hi], k, less)
+		})
+		items = items[:0]
+		for _, chunk := range chunks {
+			items = append(items, chunk...)
+		}
+	}
+	ranked := smallest(items, k, less)
+	result := make([]interface{}, len(ranked))
+	for i, item := range ranked {
+		result[i] = item.data
+	}
+	return result
+}
+
+// Largest returns the k largest items in descending order, in O(n log k)
+func Largest(arr []interface{}, k int, less func(interface{}, interface{}) bool) []interface{} {
+	return SmallestInParallel(arr, 1, k, reversed(less))
+}
+
+// LargestInParallel does the same thing as Largest, keeping a bounded heap per go routine and merging them at last
+func LargestInParallel(arr []interface{}, num int, k int, less func(interface{}, interface{}) bool) []interface{} {
+	return SmallestInParallel(arr, num, k, reversed(less))
+}
+
+func reversed(less func(interface{}, interface{}) bool) func(interface{}, interface{}) bool {
+	return func(a, b interface{}) bool {
+		return less(b, a)
 	}
 }
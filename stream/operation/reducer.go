@@ -1,6 +1,10 @@
 package operation
 
-import "github.com/dynastywind/go-stream/util"
+import (
+	"sync"
+
+	"github.com/dynastywind/go-stream/util"
+)
 
 func Reduce(arr []interface{}, init interface{}, reducer func(acc, cur interface{}) interface{}) interface{} {
 	length := len(arr)
@@ -14,16 +18,10 @@ func Reduce(arr []interface{}, init interface{}, reducer func(acc, cur interface
 	return result
 }
 
+// ReduceCombine folds every item into init with reducer
+// The combiner only merges partial results of different chunks, so it is not needed sequentially
 func ReduceCombine(arr []interface{}, init interface{}, reducer func(interface{}, interface{}) interface{}, combiner func(interface{}, interface{}) interface{}) interface{} {
-	length := len(arr)
-	if length <= 0 {
-		return init
-	}
-	result := init
-	for i := 0; i < length; i++ {
-		result = combiner(result, reducer(result, arr[i]))
-	}
-	return result
+	return Reduce(arr, init, reducer)
 }
 
 func ReduceOptional(arr []interface{}, reducer func(acc, cur interface{}) interface{}) *util.Optional {
@@ -39,3 +37,82 @@ func ReduceOptional(arr []interface{}, reducer func(acc, cur interface{}) interf
 	}
 	return util.OfNillable(result)
 }
+
+// ReduceOptionalInParallel folds every chunk of items from its first item with reducer, then merges partial results with reducer as well
+// So reducer should be associative, and commutative too unless in ordered mode, where partial results are merged in a tree of adjacent chunks
+func ReduceOptionalInParallel(arr []interface{}, num int, reducer func(interface{}, interface{}) interface{}, ordered bool) *util.Optional {
+	length := len(arr)
+	if length == 0 {
+		return util.OfEmpty()
+	}
+	if num > length {
+		num = length
+	}
+	return util.OfNillable(combinePartials(num, func(chunk int) interface{} {
+		items := arr[length*chunk/num : length*(chunk+1)/num]
+		return Reduce(items[1:], items[0], reducer)
+	}, reducer, ordered))
+}
+
+// ReduceCombineInParallel folds every chunk of items into its own copy of init with reducer, then merges partial results with combiner
+// As init is used once per chunk, it should be an identity of combiner, and combiner should be associative
+// In ordered mode, partial results are merged in a tree of adjacent chunks, so combiner needs not be commutative
+// Otherwise they are merged as soon as they are ready, in any order
+func ReduceCombineInParallel(arr []interface{}, num int, init interface{}, reducer func(interface{}, interface{}) interface{}, combiner func(interface{}, interface{}) interface{}, ordered bool) interface{} {
+	length := len(arr)
+	if length == 0 {
+		return init
+	}
+	if num > length {
+		num = length
+	}
+	return combinePartials(num, func(chunk int) interface{} {
+		return Reduce(arr[length*chunk/num:length*(chunk+1)/num], init, reducer)
+	}, combiner, ordered)
+}
+
+// combinePartials computes num partial results in their own go routines and merges them with combiner
+func combinePartials(num int, partial func(chunk int) interface{}, combiner func(interface{}, interface{}) interface{}, ordered bool) interface{} {
+	if ordered {
+		partials := make([]interface{}, num)
+		forEachChunk(num, num, func(chunk, lo, hi int) {
+			partials[chunk] = partial(chunk)
+		})
+		return combineInTree(partials, combiner)
+	}
+	results := make(chan interface{}, num)
+	for chunk := 0; chunk < num; chunk++ {
+		go func(chunk int) {
+			results <- partial(chunk)
+		}(chunk)
+	}
+	// Every merge takes two results and gives back one, until a single one is left
+	for left := num; left > 1; left-- {
+		a, b := <-results, <-results
+		go func(a, b interface{}) {
+			results <- combiner(a, b)
+		}(a, b)
+	}
+	return <-results
+}
+
+// combineInTree merges adjacent partial results pairwise, level by level, each level's merges running in parallel
+func combineInTree(partials []interface{}, combiner func(interface{}, interface{}) interface{}) interface{} {
+	for len(partials) > 1 {
+		next := make([]interface{}, (len(partials)+1)/2)
+		var wg sync.WaitGroup
+		for i := 0; i+1 < len(partials); i += 2 {
+			wg.Add(1)
+			go func(i int) {
+				defer wg.Done()
+				next[i/2] = combiner(partials[i], partials[i+1])
+			}(i)
+		}
+		if len(partials)%2 == 1 {
+			next[len(next)-1] = partials[len(partials)-1]
+		}
+		wg.Wait()
+		partials = next
+	}
+	return partials[0]
+}
@@ -0,0 +1,70 @@
+package operation
+
+import (
+	"math/rand"
+	"sort"
+)
+
+// Sample keeps k items chosen uniformly at random with reservoir sampling, in encounter order
+func Sample(arr []interface{}, k int, random *rand.Rand) []interface{} {
+	if k < 0 {
+		panic("Sample size should not be negative")
+	}
+	if k >= len(arr) {
+		return arr
+	}
+	reservoir := make([]int, k)
+	for i := range arr {
+		if i < k {
+			reservoir[i] = i
+		} else if j := intn(random, i+1); j < k {
+			reservoir[j] = i
+		}
+	}
+	sort.Ints(reservoir)
+	result := make([]interface{}, k)
+	for i, index := range reservoir {
+		result[i] = arr[index]
+	}
+	return result
+}
+
+// SampleFraction keeps every item independently with probability p, in encounter order
+func SampleFraction(arr []interface{}, p float64, random *rand.Rand) []interface{} {
+	if p < 0 || p > 1 {
+		panic("Sample fraction should be between 0 and 1")
+	}
+	result := make([]interface{}, 0)
+	for _, item := range arr {
+		if float(random) < p {
+			result = append(result, item)
+		}
+	}
+	return result
+}
+
+// Shuffle returns a uniformly random permutation of items
+func Shuffle(arr []interface{}, random *rand.Rand) []interface{} {
+	result := make([]interface{}, len(arr))
+	copy(result, arr)
+	for i := len(result) - 1; i > 0; i-- {
+		j := intn(random, i+1)
+		result[i], result[j] = result[j], result[i]
+	}
+	return result
+}
+
+// intn draws from the given source, or from the global one of math/rand if nil
+func intn(random *rand.Rand, n int) int {
+	if random == nil {
+		return rand.Intn(n)
+	}
+	return random.Intn(n)
+}
+
+func float(random *rand.Rand) float64 {
+	if random == nil {
+		return rand.Float64()
+	}
+	return random.Float64()
+}
@@ -0,0 +1,71 @@
+package operation
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+func ToCSV(arr []interface{}, w io.Writer, header []string, rowFn func(interface{}) []string) error {
+	writer := csv.NewWriter(w)
+	if header != nil {
+		if err := writer.Write(header); err != nil {
+			return err
+		}
+	}
+	for _, item := range arr {
+		if err := writer.Write(rowFn(item)); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func ToJSONLines(arr []interface{}, w io.Writer) error {
+	encoder := json.NewEncoder(w)
+	for _, item := range arr {
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func ToSQLBatches(arr []interface{}, db *sql.DB, stmt string, batchSize int, argsFn func(interface{}) []interface{}) error {
+	if batchSize < 1 {
+		return fmt.Errorf("batch size should be greater than 0, got %d", batchSize)
+	}
+	for start := 0; start < len(arr); start += batchSize {
+		end := start + batchSize
+		if end > len(arr) {
+			end = len(arr)
+		}
+		if err := insertBatch(arr[start:end], db, stmt, argsFn); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func insertBatch(batch []interface{}, db *sql.DB, stmt string, argsFn func(interface{}) []interface{}) error {
+	tx, err := db.Begin()
+	if err != nil {
+		return err
+	}
+	prepared, err := tx.Prepare(stmt)
+	if err != nil {
+		tx.Rollback()
+		return err
+	}
+	defer prepared.Close()
+	for _, item := range batch {
+		if _, err := prepared.Exec(argsFn(item)...); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+	return tx.Commit()
+}
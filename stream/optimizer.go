@@ -0,0 +1,75 @@
+package stream
+
+import "github.com/dynastywind/go-stream/util"
+
+// optimize rewrites a pipeline into an equivalent one doing less work, applying rules on adjacent stages until none matches
+// The second result is false if no rule matched, the pipeline being returned as is
+//
+// Rules are:
+//   - Adjacent maps, or adjacent filters, of the same kind are fused into one stage
+//   - A limit is moved before a map keeping encounter order, so that fewer items are mapped
+//   - Adjacent limits are fused into the smallest one
+//   - A sort followed by a sort which does not keep equal items in their order is dropped
+//   - A sort followed by a limit becomes a bounded heap keeping the smallest items
+func optimize(descriptors []OperationDescriptor, parallel bool) ([]OperationDescriptor, bool) {
+	result := descriptors
+	changed := false
+	for i := 0; i+1 < len(result); {
+		rewritten, ok := rewrite(result[i], result[i+1], parallel)
+		if !ok {
+			i++
+			continue
+		}
+		// Always copy, so that the recorded pipeline is never modified
+		next := make([]OperationDescriptor, 0, len(result)-2+len(rewritten))
+		next = append(next, result[:i]...)
+		next = append(next, rewritten...)
+		result = append(next, result[i+2:]...)
+		changed = true
+		// A rewritten stage may now match a rule with the one before it
+		if i > 0 {
+			i--
+		}
+	}
+	return result, changed
+}
+
+func rewrite(first, second OperationDescriptor, parallel bool) ([]OperationDescriptor, bool) {
+	switch {
+	case first.tag == second.tag && (first.tag == MAP || first.tag == MAP_ORDERED):
+		f := first.params[0].(func(interface{}) interface{})
+		g := second.params[0].(func(interface{}) interface{})
+		return []OperationDescriptor{{
+			tag: first.tag,
+			params: []interface{}{func(item interface{}) interface{} {
+				return g(f(item))
+			}},
+		}}, true
+	case first.tag == second.tag && (first.tag == FILTER || first.tag == FILTER_ORDERED):
+		p := first.params[0].(func(interface{}) bool)
+		q := second.params[0].(func(interface{}) bool)
+		return []OperationDescriptor{{
+			tag: first.tag,
+			params: []interface{}{func(item interface{}) bool {
+				return p(item) && q(item)
+			}},
+		}}, true
+	case second.tag == LIMIT && (first.tag == MAP_ORDERED || first.tag == MAP && !parallel):
+		return []OperationDescriptor{second, first}, true
+	case first.tag == LIMIT && second.tag == LIMIT:
+		if first.params[0].(int) < second.params[0].(int) {
+			return []OperationDescriptor{first}, true
+		}
+		return []OperationDescriptor{second}, true
+	case first.tag == SORTED && second.tag == SORTED:
+		if stability, ok := second.params[1].(util.Stability); ok && !stability.Stable() {
+			return []OperationDescriptor{second}, true
+		}
+	case first.tag == SORTED && first.params[1] == nil && second.tag == LIMIT && second.params[0].(int) >= 0:
+		return []OperationDescriptor{{
+			tag:    BOTTOM_K,
+			params: []interface{}{second.params[0], first.params[0]},
+		}}, true
+	}
+	return nil, false
+}
@@ -0,0 +1,384 @@
+package stream
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"math"
+	"math/rand"
+	"reflect"
+	"time"
+
+	"github.com/dynastywind/go-stream/expr"
+	"github.com/dynastywind/go-stream/util"
+)
+
+// PipelineDefinition describes a pipeline declaratively, so that it can be read from configuration
+// Its fields are tagged for JSON, and for YAML libraries following the same conventions
+type PipelineDefinition struct {
+	// Parallel is the number of go routines the pipeline runs with, 0 keeping the execution mode of the source stream
+	Parallel int `json:"parallel,omitempty" yaml:"parallel,omitempty"`
+	// Seed, if set, seeds the random source of the pipeline, so that sampling and shuffling are reproducible
+	Seed  *int64           `json:"seed,omitempty" yaml:"seed,omitempty"`
+	Steps []StepDefinition `json:"steps" yaml:"steps"`
+}
+
+// StepDefinition describes an intermediate operation of a pipeline
+//
+// Op is the tag of a built-in or registered operation, like FILTER or LIMIT
+// Params are given in the order of the matching stream method's parameters
+// A function parameter is referenced by name as {"fn": "name"}, other parameters being numbers or strings
+// Predicates, mappers and comparators can also be written inline as {"expr": "latency_ms > 200"}, see package expr
+// Durations are written as strings like "5m"
+type StepDefinition struct {
+	Op     string        `json:"op" yaml:"op"`
+	Params []interface{} `json:"params,omitempty" yaml:"params,omitempty"`
+}
+
+// Functions resolves the function names referenced by pipeline definitions
+type Functions map[string]interface{}
+
+// PipelineError describes an invalid part of a pipeline definition
+type PipelineError struct {
+	// Path locates the invalid part, like steps[2].params[0]
+	Path string
+	Err  error
+}
+
+func (e *PipelineError) Error() string {
+	return fmt.Sprintf("%s: %v", e.Path, e.Err)
+}
+
+func (e *PipelineError) Unwrap() error {
+	return e.Err
+}
+
+type paramKind int
+
+const (
+	intParam paramKind = iota
+	floatParam
+	durationParam
+	functionParam
+)
+
+type paramSpec struct {
+	kind paramKind
+	// function is the type a function parameter should be convertible to, or an interface it should implement
+	function reflect.Type
+	optional bool
+	// check rejects numbers and durations out of the range the operation accepts, if not nil
+	check func(float64) error
+}
+
+func atLeast(min float64) func(float64) error {
+	return func(f float64) error {
+		switch {
+		case f >= min:
+			return nil
+		case min == 0:
+			return fmt.Errorf("should not be negative, got %v", f)
+		case min == 1:
+			return fmt.Errorf("should be positive, got %v", f)
+		}
+		return fmt.Errorf("should be at least %v, got %v", min, f)
+	}
+}
+
+func between(min, max float64, exclusive bool) func(float64) error {
+	return func(f float64) error {
+		if exclusive && (f <= min || f >= max) {
+			return fmt.Errorf("should be between %v and %v exclusive, got %v", min, max, f)
+		}
+		if f < min || f > max {
+			return fmt.Errorf("should be between %v and %v, got %v", min, max, f)
+		}
+		return nil
+	}
+}
+
+var (
+	countParam    = paramSpec{kind: intParam, check: atLeast(0)}
+	capacityParam = paramSpec{kind: intParam, check: atLeast(1)}
+	fractionParam = paramSpec{kind: floatParam, check: between(0, 1, false)}
+	rateParam     = paramSpec{kind: floatParam, check: between(0, 1, true)}
+	windowParam   = paramSpec{kind: durationParam, check: func(f float64) error {
+		if f <= 0 {
+			return fmt.Errorf("should be positive, got %v", time.Duration(f))
+		}
+		return nil
+	}}
+	consumerParam   = paramSpec{kind: functionParam, function: reflect.TypeOf((func(interface{}))(nil))}
+	flatMapperParam = paramSpec{kind: functionParam, function: reflect.TypeOf((func(interface{}) []interface{})(nil))}
+	hashParam       = paramSpec{kind: functionParam, function: reflect.TypeOf((func(interface{}) string)(nil))}
+	keyParam        = paramSpec{kind: functionParam, function: reflect.TypeOf((func(interface{}) interface{})(nil))}
+	lessParam       = paramSpec{kind: functionParam, function: reflect.TypeOf((func(interface{}, interface{}) bool)(nil))}
+	predicateParam  = paramSpec{kind: functionParam, function: reflect.TypeOf((func(interface{}) bool)(nil))}
+	sorterParam     = paramSpec{kind: functionParam, function: reflect.TypeOf((*util.Sorter)(nil)).Elem(), optional: true}
+	timestampParam  = paramSpec{kind: functionParam, function: reflect.TypeOf((func(interface{}) time.Time)(nil))}
+)
+
+var paramSpecs = map[OperationTag][]paramSpec{
+	BOTTOM_K:             {countParam, lessParam},
+	DISTINCT:             {hashParam},
+	DISTINCT_APPROX:      {hashParam, capacityParam, rateParam},
+	DISTINCT_BY:          {keyParam},
+	DISTINCT_WITHIN:      {hashParam, capacityParam},
+	DISTINCT_WITHIN_TIME: {hashParam, timestampParam, windowParam},
+	FILTER:               {predicateParam},
+	FILTER_ORDERED:       {predicateParam},
+	FLAT_MAP:             {flatMapperParam},
+	FLAT_MAP_ORDERED:     {flatMapperParam},
+	LIMIT:                {countParam},
+	MAP:                  {keyParam},
+	MAP_ORDERED:          {keyParam},
+	PEEK:                 {consumerParam},
+	REVERSE:              {},
+	SAMPLE:               {countParam},
+	SAMPLE_FRACTION:      {fractionParam},
+	SHUFFLE:              {},
+	SKIP:                 {countParam},
+	SORTED:               {lessParam, sorterParam},
+	TOP_K:                {countParam, lessParam},
+}
+
+// ParsePipeline reads a pipeline definition written in JSON, rejecting unknown fields
+func ParsePipeline(r io.Reader) (*PipelineDefinition, error) {
+	decoder := json.NewDecoder(r)
+	decoder.UseNumber()
+	decoder.DisallowUnknownFields()
+	def := &PipelineDefinition{}
+	if err := decoder.Decode(def); err != nil {
+		return nil, fmt.Errorf("invalid pipeline definition: %w", err)
+	}
+	return def, nil
+}
+
+// Build adds the operations of the definition onto a source stream
+// Nothing is built if any step is invalid, the error being a *PipelineError locating it
+//
+// @param source	Stream to read data items from
+// @param functions	Functions referenced by the definition
+// @return			A stream after applying every step
+func (def *PipelineDefinition) Build(source Stream, functions Functions) (Stream, error) {
+	if def.Parallel < 0 {
+		return nil, &PipelineError{Path: "parallel", Err: fmt.Errorf("number of go routines should not be negative, got %d", def.Parallel)}
+	}
+	descriptors := make([]OperationDescriptor, len(def.Steps))
+	for i, step := range def.Steps {
+		desc, err := step.resolve(fmt.Sprintf("steps[%d]", i), functions)
+		if err != nil {
+			return nil, err
+		}
+		descriptors[i] = desc
+	}
+	if def.Parallel > 0 {
+		source = source.AsParallel(def.Parallel)
+	}
+	if def.Seed != nil {
+		source = source.WithRandom(rand.New(rand.NewSource(*def.Seed)))
+	}
+	return Transform(source, descriptors), nil
+}
+
+func (step StepDefinition) resolve(path string, functions Functions) (OperationDescriptor, error) {
+	tag := OperationTag(step.Op)
+	specs, builtIn := paramSpecs[tag]
+	if !builtIn {
+		if _, ok := registered(tag); !ok {
+			return OperationDescriptor{}, &PipelineError{Path: path + ".op", Err: fmt.Errorf("unknown operation %q", step.Op)}
+		}
+		// Registered operations declare no parameters, which are handed over as written
+		params := make([]interface{}, len(step.Params))
+		for i, param := range step.Params {
+			value, err := resolveAny(param, functions)
+			if err != nil {
+				return OperationDescriptor{}, &PipelineError{Path: fmt.Sprintf("%s.params[%d]", path, i), Err: err}
+			}
+			params[i] = value
+		}
+		return OperationDescriptor{tag: tag, params: params}, nil
+	}
+	required := 0
+	for _, spec := range specs {
+		if !spec.optional {
+			required++
+		}
+	}
+	if len(step.Params) < required || len(step.Params) > len(specs) {
+		return OperationDescriptor{}, &PipelineError{Path: path + ".params", Err: fmt.Errorf("%s takes %s, got %d", tag, countParams(required, len(specs)), len(step.Params))}
+	}
+	params := make([]interface{}, len(specs))
+	for i, param := range step.Params {
+		value, err := specs[i].resolve(param, functions)
+		if err != nil {
+			return OperationDescriptor{}, &PipelineError{Path: fmt.Sprintf("%s.params[%d]", path, i), Err: err}
+		}
+		params[i] = value
+	}
+	return OperationDescriptor{tag: tag, params: params}, nil
+}
+
+func (spec paramSpec) checked(f float64) error {
+	if spec.check == nil {
+		return nil
+	}
+	return spec.check(f)
+}
+
+func countParams(required, total int) string {
+	plural := func(n int) string {
+		if n == 1 {
+			return "1 parameter"
+		}
+		return fmt.Sprintf("%d parameters", n)
+	}
+	if required == total {
+		return plural(total)
+	}
+	return fmt.Sprintf("%d to %s", required, plural(total))
+}
+
+func (spec paramSpec) resolve(param interface{}, functions Functions) (interface{}, error) {
+	switch spec.kind {
+	case intParam:
+		i, err := toInt(param)
+		if err == nil {
+			err = spec.checked(float64(i))
+		}
+		return i, err
+	case floatParam:
+		f, ok := toNumber(param)
+		if !ok {
+			return nil, fmt.Errorf("expected a number, got %s", describe(param))
+		}
+		return f, spec.checked(f)
+	case durationParam:
+		s, ok := param.(string)
+		if !ok {
+			return nil, fmt.Errorf("expected a duration like \"5m\", got %s", describe(param))
+		}
+		d, err := time.ParseDuration(s)
+		if err == nil {
+			err = spec.checked(float64(d))
+		}
+		return d, err
+	}
+	if src, ok := reference(param, "expr"); ok {
+		return compileExpression(src, spec.function)
+	}
+	name, err := functionName(param)
+	if err != nil {
+		return nil, err
+	}
+	fn, ok := functions[name]
+	if !ok {
+		return nil, fmt.Errorf("function %q is not registered", name)
+	}
+	value := reflect.ValueOf(fn)
+	if fn == nil || !value.Type().ConvertibleTo(spec.function) {
+		return nil, fmt.Errorf("function %q is a %T, expected %v", name, fn, spec.function)
+	}
+	return value.Convert(spec.function).Interface(), nil
+}
+
+// resolveAny resolves function references, and turns integral numbers into int and other numbers into float64
+func resolveAny(param interface{}, functions Functions) (interface{}, error) {
+	if name, err := functionName(param); err == nil {
+		fn, ok := functions[name]
+		if !ok {
+			return nil, fmt.Errorf("function %q is not registered", name)
+		}
+		return fn, nil
+	}
+	if f, ok := toNumber(param); ok {
+		if f == math.Trunc(f) && math.Abs(f) <= math.MaxInt32 {
+			return int(f), nil
+		}
+		return f, nil
+	}
+	return param, nil
+}
+
+// compileExpression turns an expression into a function of the expected type
+func compileExpression(src string, function reflect.Type) (interface{}, error) {
+	if function == lessParam.function {
+		return expr.CompileLess(src)
+	}
+	if function != predicateParam.function && function != keyParam.function {
+		return nil, fmt.Errorf("expected a %v, which cannot be written as an expression", function)
+	}
+	e, err := expr.Compile(src)
+	if err != nil {
+		return nil, err
+	}
+	if function == predicateParam.function {
+		return e.Predicate(), nil
+	}
+	return e.Mapper(), nil
+}
+
+func functionName(param interface{}) (string, error) {
+	if name, ok := reference(param, "fn"); ok {
+		return name, nil
+	}
+	return "", fmt.Errorf("expected a function reference like {\"fn\": \"name\"} or {\"expr\": \"...\"}, got %s", describe(param))
+}
+
+// reference returns the string held by an object made of the given key only
+func reference(param interface{}, key string) (string, bool) {
+	var value interface{}
+	var size int
+	switch ref := param.(type) {
+	case map[string]interface{}:
+		value, size = ref[key], len(ref)
+	case map[interface{}]interface{}:
+		value, size = ref[key], len(ref)
+	}
+	s, ok := value.(string)
+	return s, ok && size == 1
+}
+
+func toNumber(param interface{}) (float64, bool) {
+	switch n := param.(type) {
+	case json.Number:
+		f, err := n.Float64()
+		return f, err == nil
+	case float64:
+		return n, true
+	case float32:
+		return float64(n), true
+	case int:
+		return float64(n), true
+	case int64:
+		return float64(n), true
+	case uint64:
+		return float64(n), true
+	}
+	return 0, false
+}
+
+func toInt(param interface{}) (int, error) {
+	if n, ok := param.(json.Number); ok {
+		if i, err := n.Int64(); err == nil {
+			return int(i), nil
+		}
+	}
+	f, ok := toNumber(param)
+	if !ok || f != math.Trunc(f) {
+		return 0, fmt.Errorf("expected an integer, got %s", describe(param))
+	}
+	return int(f), nil
+}
+
+func describe(param interface{}) string {
+	switch param.(type) {
+	case nil:
+		return "null"
+	case string:
+		return fmt.Sprintf("string %q", param)
+	case json.Number, float64, float32, int, int64, uint64:
+		return fmt.Sprintf("number %v", param)
+	}
+	return fmt.Sprintf("%T %v", param, param)
+}
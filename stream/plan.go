@@ -0,0 +1,153 @@
+package stream
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Stage describes an intermediate operation of a pipeline
+type Stage struct {
+	Tag OperationTag
+	// Args holds the scalar parameters of the operation, like the number of items kept by LIMIT, functions being left out
+	Args []interface{}
+	// Ordered is true if the items leaving the stage follow the order they entered it, or a sorting order
+	Ordered bool
+	// Stateful is true if the stage needs to see several items at once, rather than handling each one on its own
+	Stateful bool
+}
+
+// Plan describes what a pipeline will do once evaluated
+type Plan struct {
+	Parallel bool
+	// Routines is the number of go routines used by a parallel pipeline, 1 for a sequential one
+	Routines int
+	Stages   []Stage
+}
+
+type stageTraits struct {
+	stateful bool
+	// unorderedInParallel is true for operations giving up encounter order in parallel streams only
+	unorderedInParallel bool
+	// unordered is true for operations never keeping encounter order
+	unordered bool
+}
+
+var traits = map[OperationTag]stageTraits{
+	BOTTOM_K:             {stateful: true},
+	DISTINCT:             {stateful: true},
+	DISTINCT_APPROX:      {stateful: true},
+	DISTINCT_BY:          {stateful: true},
+	DISTINCT_WITHIN:      {stateful: true},
+	DISTINCT_WITHIN_TIME: {stateful: true},
+	FILTER:               {unorderedInParallel: true},
+	FILTER_ORDERED:       {},
+	FLAT_MAP:             {unorderedInParallel: true},
+	FLAT_MAP_ORDERED:     {},
+	LIMIT:                {stateful: true},
+	MAP:                  {unorderedInParallel: true},
+	MAP_ORDERED:          {},
+	PEEK:                 {},
+	REVERSE:              {stateful: true},
+	SAMPLE:               {stateful: true},
+	SAMPLE_FRACTION:      {},
+	SHUFFLE:              {stateful: true, unordered: true},
+	SKIP:                 {stateful: true},
+	SORTED:               {stateful: true},
+	TOP_K:                {stateful: true},
+}
+
+func explain(descriptors []OperationDescriptor, parallel bool, routines int) *Plan {
+	plan := &Plan{
+		Parallel: parallel,
+		Routines: routines,
+		Stages:   make([]Stage, len(descriptors)),
+	}
+	for i, desc := range descriptors {
+		t := traitsOf(desc.tag)
+		plan.Stages[i] = Stage{
+			Tag:      desc.tag,
+			Args:     scalarArgs(desc.params),
+			Ordered:  !t.unordered && !(parallel && t.unorderedInParallel),
+			Stateful: t.stateful,
+		}
+	}
+	return plan
+}
+
+func scalarArgs(params []interface{}) []interface{} {
+	var args []interface{}
+	for _, param := range params {
+		if param == nil {
+			continue
+		}
+		switch reflect.TypeOf(param).Kind() {
+		case reflect.Bool, reflect.String,
+			reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+			reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+			reflect.Float32, reflect.Float64:
+			args = append(args, param)
+		}
+	}
+	return args
+}
+
+// Name returns the tag of the stage followed by its arguments, like LIMIT(10)
+func (stage Stage) Name() string {
+	if len(stage.Args) == 0 {
+		return string(stage.Tag)
+	}
+	args := make([]string, len(stage.Args))
+	for i, arg := range stage.Args {
+		args[i] = fmt.Sprint(arg)
+	}
+	return fmt.Sprintf("%s(%s)", stage.Tag, strings.Join(args, ", "))
+}
+
+func (stage Stage) traits() string {
+	state, order := "stateless", "ordered"
+	if stage.Stateful {
+		state = "stateful"
+	}
+	if !stage.Ordered {
+		order = "unordered"
+	}
+	return state + ", " + order
+}
+
+func (plan *Plan) source() string {
+	if plan.Parallel {
+		return fmt.Sprintf("parallel stream with %d routines", plan.Routines)
+	}
+	return "sequential stream"
+}
+
+// String lists the stages of the plan, one per line
+func (plan *Plan) String() string {
+	var b strings.Builder
+	b.WriteString(plan.source())
+	for i, stage := range plan.Stages {
+		fmt.Fprintf(&b, "\n%d. %s [%s]", i+1, stage.Name(), stage.traits())
+	}
+	return b.String()
+}
+
+// DOT renders the plan as a Graphviz graph, from the source to the last stage
+func (plan *Plan) DOT() string {
+	var b strings.Builder
+	b.WriteString("digraph pipeline {\n\trankdir=LR;\n")
+	fmt.Fprintf(&b, "\tsource [shape=box, label=%q];\n", plan.source())
+	previous := "source"
+	for i, stage := range plan.Stages {
+		node := fmt.Sprintf("stage%d", i+1)
+		style := ""
+		if stage.Stateful {
+			style = ", style=bold"
+		}
+		fmt.Fprintf(&b, "\t%s [label=%q%s];\n", node, stage.Name()+"\n"+stage.traits(), style)
+		fmt.Fprintf(&b, "\t%s -> %s;\n", previous, node)
+		previous = node
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,75 @@
+package stream
+
+import (
+	"fmt"
+	"sync"
+)
+
+// Operation describes a user-defined intermediate operation, which streams add with Apply once registered
+// Like built-in operations, it is carried over by AsParallel and AsSequence, and described by Explain
+type Operation struct {
+	Tag OperationTag
+	// Sequential applies the operation onto the items of a sequential stream, params being those given to Apply
+	Sequential func(arr []interface{}, params []interface{}) []interface{}
+	// Parallel applies the operation onto the items of a parallel stream with the given number of go routines
+	// Sequential is used instead if nil
+	Parallel func(arr []interface{}, routines int, params []interface{}) []interface{}
+	// Stateful is true if the operation needs to see several items at once, rather than handling each one on its own
+	Stateful bool
+	// Unordered is true if the operation never keeps encounter order
+	Unordered bool
+	// UnorderedInParallel is true if the operation keeps encounter order in sequential streams only
+	UnorderedInParallel bool
+}
+
+var registry = struct {
+	sync.RWMutex
+	operations map[OperationTag]Operation
+}{
+	operations: make(map[OperationTag]Operation),
+}
+
+// Register makes an operation available to every stream
+// It panics if the operation has no tag or no sequential implementation, or if its tag is already taken
+func Register(op Operation) {
+	if op.Tag == "" || op.Sequential == nil {
+		panic("Operation should have a tag and a sequential implementation")
+	}
+	registry.Lock()
+	defer registry.Unlock()
+	if _, ok := traits[op.Tag]; ok {
+		panic(fmt.Sprintf("Operation type %v is built in", op.Tag))
+	}
+	if _, ok := registry.operations[op.Tag]; ok {
+		panic(fmt.Sprintf("Operation type %v is already registered", op.Tag))
+	}
+	registry.operations[op.Tag] = op
+}
+
+func registered(tag OperationTag) (Operation, bool) {
+	registry.RLock()
+	defer registry.RUnlock()
+	op, ok := registry.operations[tag]
+	return op, ok
+}
+
+func mustBeRegistered(tag OperationTag) Operation {
+	op, ok := registered(tag)
+	if !ok {
+		panic(fmt.Sprintf("Unregistered operation type found: %v", tag))
+	}
+	return op
+}
+
+// traitsOf returns the characteristics of a built-in or registered operation
+func traitsOf(tag OperationTag) stageTraits {
+	if t, ok := traits[tag]; ok {
+		return t
+	}
+	op, _ := registered(tag)
+	return stageTraits{
+		stateful:            op.Stateful,
+		unordered:           op.Unordered,
+		unorderedInParallel: op.UnorderedInParallel,
+	}
+}
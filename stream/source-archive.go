@@ -0,0 +1,247 @@
+package stream
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bufio"
+	"bytes"
+	"compress/bzip2"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"strings"
+	"time"
+)
+
+// ArchiveEntry is an item of a stream built by FromTar or FromZip
+type ArchiveEntry struct {
+	Name    string
+	Size    int64
+	Mode    fs.FileMode
+	ModTime time.Time
+	// Header is the original *tar.Header or *zip.FileHeader of the entry
+	Header interface{}
+	open   func() (io.ReadCloser, error)
+}
+
+// Open returns a reader of the entry content, which should be closed by the caller
+// Nothing is extracted until this method is called, unless FromTar was asked to buffer entry contents
+//
+// @return	A reader of the entry content
+func (e ArchiveEntry) Open() (io.ReadCloser, error) {
+	return e.open()
+}
+
+// IsDir returns true if this entry describes a directory
+//
+// @return	True if this entry is a directory, false otherwise
+func (e ArchiveEntry) IsDir() bool {
+	return e.Mode.IsDir()
+}
+
+// TarOptions configures how FromTar reads an archive
+type TarOptions struct {
+	// BufferSize, if positive, is the total size in bytes of entry contents kept in memory while the archive is scanned,
+	// entries fitting in what is left being opened without reading the archive again
+	// Contents are never buffered for an uncompressed archive opened as an io.ReadSeeker, which can seek to them instead
+	BufferSize int64
+	// OnError receives errors met while reading the archive
+	OnError ErrorHandler
+}
+
+// FromTar returns a sequential stream of the entries of a tar archive, optionally compressed by gzip or bzip2
+// Entries are opened lazily: if open returns an io.ReadSeeker of an uncompressed archive, opening an entry seeks to its content,
+// otherwise tar archives can only be read forward, so open is called again and the archive is scanned up to that entry
+// Set options.BufferSize to trade memory for those scans
+//
+// @param open		Function to open the archive, called when the stream is evaluated
+// @param options	Reading options
+// @return			A sequential stream of ArchiveEntry
+func FromTar(open func() (io.ReadCloser, error), options TarOptions) Stream {
+	return fromSource(func() []interface{} {
+		result := make([]interface{}, 0)
+		file, err := open()
+		if err != nil {
+			options.OnError.report(err)
+			return result
+		}
+		defer file.Close()
+		reader, seeker, err := tarReader(file)
+		if err != nil {
+			options.OnError.report(err)
+			return result
+		}
+		buffered := int64(0)
+		for index := 0; ; index++ {
+			header, err := reader.Next()
+			if err == io.EOF {
+				return result
+			}
+			var offset int64
+			if err == nil && seeker != nil {
+				offset, err = seeker.Seek(0, io.SeekCurrent)
+			}
+			var content []byte
+			if err == nil && seeker == nil && buffered+header.Size <= options.BufferSize {
+				content, err = io.ReadAll(reader)
+				buffered += header.Size
+			}
+			if err != nil {
+				options.OnError.report(&RecordError{Index: index + 1, Err: err})
+				return result
+			}
+			entry := ArchiveEntry{
+				Name:    header.Name,
+				Size:    header.Size,
+				Mode:    header.FileInfo().Mode(),
+				ModTime: header.ModTime,
+				Header:  header,
+			}
+			switch {
+			case seeker != nil && contiguous(header):
+				entry.open = seekTarEntry(open, offset, header.Size)
+			case content != nil:
+				entry.open = openContent(content)
+			default:
+				entry.open = scanTarEntry(open, index)
+			}
+			result = append(result, entry)
+		}
+	})
+}
+
+// FromZip returns a sequential stream of the entries of a zip archive
+//
+// @param r			Content of the archive, read when the stream is evaluated
+// @param size		Size of the archive in bytes
+// @param onError	Handler of errors met while reading the archive
+// @return			A sequential stream of ArchiveEntry
+func FromZip(r io.ReaderAt, size int64, onError ErrorHandler) Stream {
+	return fromSource(func() []interface{} {
+		result := make([]interface{}, 0)
+		reader, err := zip.NewReader(r, size)
+		if err != nil {
+			onError.report(err)
+			return result
+		}
+		for _, file := range reader.File {
+			result = append(result, ArchiveEntry{
+				Name:    file.Name,
+				Size:    int64(file.UncompressedSize64),
+				Mode:    file.Mode(),
+				ModTime: file.Modified,
+				Header:  &file.FileHeader,
+				open:    file.Open,
+			})
+		}
+		return result
+	})
+}
+
+func openContent(content []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(content)), nil
+	}
+}
+
+// contiguous tells whether the content of an entry is stored as is right after its header, which sparse files are not
+func contiguous(header *tar.Header) bool {
+	if header.Typeflag != tar.TypeReg && header.Typeflag != tar.TypeRegA {
+		return false
+	}
+	for key := range header.PAXRecords {
+		if strings.HasPrefix(key, "GNU.sparse.") {
+			return false
+		}
+	}
+	return true
+}
+
+func seekTarEntry(open func() (io.ReadCloser, error), offset, size int64) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		file, err := open()
+		if err != nil {
+			return nil, err
+		}
+		seeker, ok := file.(io.ReadSeeker)
+		if !ok {
+			file.Close()
+			return nil, fmt.Errorf("archive was first opened as an io.ReadSeeker, got %T", file)
+		}
+		if _, err := seeker.Seek(offset, io.SeekStart); err != nil {
+			file.Close()
+			return nil, err
+		}
+		return tarEntryReader{
+			Reader: io.LimitReader(seeker, size),
+			Closer: file,
+		}, nil
+	}
+}
+
+func scanTarEntry(open func() (io.ReadCloser, error), index int) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		file, err := open()
+		if err != nil {
+			return nil, err
+		}
+		reader, _, err := tarReader(file)
+		if err != nil {
+			file.Close()
+			return nil, err
+		}
+		for i := 0; i <= index; i++ {
+			if _, err := reader.Next(); err != nil {
+				file.Close()
+				if err == io.EOF {
+					err = io.ErrUnexpectedEOF
+				}
+				return nil, err
+			}
+		}
+		return tarEntryReader{
+			Reader: reader,
+			Closer: file,
+		}, nil
+	}
+}
+
+type tarEntryReader struct {
+	io.Reader
+	io.Closer
+}
+
+// tarReader detects gzip and bzip2 compression by their magic numbers
+// The input is returned as an io.ReadSeeker when it is one and is not compressed, as entries can then be found again by seeking
+func tarReader(r io.Reader) (*tar.Reader, io.ReadSeeker, error) {
+	if seeker, ok := r.(io.ReadSeeker); ok {
+		magic := make([]byte, 3)
+		n, _ := io.ReadFull(seeker, magic)
+		if _, err := seeker.Seek(int64(-n), io.SeekCurrent); err != nil {
+			return nil, nil, err
+		}
+		if !compressed(magic[:n]) {
+			// Nothing should read ahead of the tar reader, so that the input position is where the entry content starts
+			return tar.NewReader(seeker), seeker, nil
+		}
+	}
+	buffered := bufio.NewReader(r)
+	magic, _ := buffered.Peek(3)
+	switch {
+	case bytes.HasPrefix(magic, []byte{0x1f, 0x8b}):
+		decompressed, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, nil, err
+		}
+		return tar.NewReader(decompressed), nil, nil
+	case bytes.HasPrefix(magic, []byte("BZh")):
+		return tar.NewReader(bzip2.NewReader(buffered)), nil, nil
+	default:
+		return tar.NewReader(buffered), nil, nil
+	}
+}
+
+func compressed(magic []byte) bool {
+	return bytes.HasPrefix(magic, []byte{0x1f, 0x8b}) || bytes.HasPrefix(magic, []byte("BZh"))
+}
@@ -0,0 +1,176 @@
+package stream
+
+import (
+	"encoding"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"strconv"
+)
+
+// CSVOptions configures how FromCSV reads its input
+type CSVOptions struct {
+	// Comma is the field delimiter, ',' if left zero
+	Comma rune
+	// Comment, if not zero, marks lines to be ignored when found at their beginning
+	Comment          rune
+	LazyQuotes       bool
+	TrimLeadingSpace bool
+	// Header treats the first record as column names and yields every row as a map[string]string
+	Header bool
+	// Type, if set, decodes header-keyed rows into values of this struct type
+	// Columns are matched against the `csv` tag of each field, or the field name when no tag is present
+	Type reflect.Type
+	// OnError receives per-row errors, wrapped in a *RecordError
+	OnError ErrorHandler
+}
+
+// FromCSV returns a sequential stream reading rows from a CSV input
+// Rows are yielded as []string, as map[string]string when a header is used, or as values of options.Type
+// Rows failing to be parsed or decoded are reported to options.OnError and skipped
+//
+// @param r			CSV input, read when the stream is evaluated
+// @param options	Reading options
+// @return			A sequential stream of CSV rows
+func FromCSV(r io.Reader, options CSVOptions) Stream {
+	return fromSource(func() []interface{} {
+		return readCSV(r, options)
+	})
+}
+
+func readCSV(r io.Reader, options CSVOptions) []interface{} {
+	reader := csv.NewReader(r)
+	if options.Comma != 0 {
+		reader.Comma = options.Comma
+	}
+	reader.Comment = options.Comment
+	reader.LazyQuotes = options.LazyQuotes
+	reader.TrimLeadingSpace = options.TrimLeadingSpace
+
+	if options.Type != nil && options.Type.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("CSV rows can only be decoded into struct types, got %v", options.Type))
+	}
+	var header []string
+	if options.Header || options.Type != nil {
+		record, err := reader.Read()
+		if err != nil {
+			if err != io.EOF {
+				options.OnError.report(fmt.Errorf("reading CSV header: %w", err))
+			}
+			return []interface{}{}
+		}
+		header = record
+	}
+	var fields map[string]int
+	if options.Type != nil {
+		fields = csvFields(options.Type)
+	}
+
+	result := make([]interface{}, 0)
+	for index := 1; ; index++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			options.OnError.report(&RecordError{Index: index, Err: err})
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				continue
+			}
+			break
+		}
+		switch {
+		case options.Type != nil:
+			value, err := decodeCSVRecord(header, record, options.Type, fields)
+			if err != nil {
+				options.OnError.report(&RecordError{Index: index, Err: err})
+				continue
+			}
+			result = append(result, value)
+		case header != nil:
+			row := make(map[string]string, len(header))
+			for i, name := range header {
+				row[name] = record[i]
+			}
+			result = append(result, row)
+		default:
+			result = append(result, record)
+		}
+	}
+	return result
+}
+
+func csvFields(t reflect.Type) map[string]int {
+	fields := make(map[string]int)
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.PkgPath != "" {
+			continue
+		}
+		name := field.Tag.Get("csv")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[name] = i
+	}
+	return fields
+}
+
+func decodeCSVRecord(header, record []string, t reflect.Type, fields map[string]int) (interface{}, error) {
+	value := reflect.New(t).Elem()
+	for i, name := range header {
+		index, ok := fields[name]
+		if !ok {
+			continue
+		}
+		if err := setCSVField(value.Field(index), record[i]); err != nil {
+			return nil, fmt.Errorf("column %q: %w", name, err)
+		}
+	}
+	return value.Interface(), nil
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+func setCSVField(field reflect.Value, text string) error {
+	if field.Addr().Type().Implements(textUnmarshalerType) {
+		return field.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(text))
+	}
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(text)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(text)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(text, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(text, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(text, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	default:
+		return fmt.Errorf("unsupported field type %v", field.Type())
+	}
+	return nil
+}
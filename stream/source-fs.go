@@ -0,0 +1,160 @@
+package stream
+
+import (
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strings"
+)
+
+// SymlinkPolicy decides how FromFS deals with symbolic links
+type SymlinkPolicy int
+
+const (
+	// SymlinkKeep yields symbolic links as they are, without resolving them
+	SymlinkKeep SymlinkPolicy = iota
+	// SymlinkSkip leaves symbolic links out
+	SymlinkSkip
+	// SymlinkFollow resolves symbolic links and walks into linked directories
+	// A link to a directory containing it is yielded but not walked into, the cycle being reported to OnError
+	SymlinkFollow
+)
+
+// FSOptions configures how FromFS walks a file system
+type FSOptions struct {
+	// Pattern, if not empty, keeps only entries whose base name matches this glob, as defined by path.Match
+	Pattern string
+	// MaxDepth limits how deep the walk goes, entries directly under root being at depth 1
+	// Zero means no limit
+	MaxDepth int
+	// Dirs yields directories as well as files
+	Dirs     bool
+	Symlinks SymlinkPolicy
+	// OnError receives errors met while walking, the failing part of the tree being skipped
+	OnError ErrorHandler
+}
+
+// FileEntry is an item of a stream built by FromFS
+type FileEntry struct {
+	fs.DirEntry
+	// Path is the slash-separated path of the entry in its file system
+	Path  string
+	Depth int
+}
+
+// FromFS returns a sequential stream of the entries found under root in a file system, in lexical order
+// The root itself is not part of the stream
+//
+// @param fsys		File system to walk, walked when the stream is evaluated
+// @param root		Directory to start from
+// @param options	Walking options
+// @return			A sequential stream of FileEntry
+func FromFS(fsys fs.FS, root string, options FSOptions) Stream {
+	return fromSource(func() []interface{} {
+		if _, err := path.Match(options.Pattern, ""); err != nil {
+			options.OnError.report(err)
+			return []interface{}{}
+		}
+		result := make([]interface{}, 0)
+		walkFS(fsys, root, 0, options, &result)
+		return result
+	})
+}
+
+func walkFS(fsys fs.FS, root string, base int, options FSOptions, result *[]interface{}) {
+	fs.WalkDir(fsys, root, func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			options.OnError.report(err)
+			return nil
+		}
+		if name == root {
+			return nil
+		}
+		depth := base + fsDepth(root, name)
+		if options.MaxDepth > 0 && depth > options.MaxDepth {
+			if entry.IsDir() {
+				return fs.SkipDir
+			}
+			return nil
+		}
+		if entry.Type()&fs.ModeSymlink != 0 {
+			switch options.Symlinks {
+			case SymlinkSkip:
+				return nil
+			case SymlinkFollow:
+				info, err := fs.Stat(fsys, name)
+				if err != nil {
+					options.OnError.report(err)
+					return nil
+				}
+				entry = fileInfoEntry{info}
+			}
+		}
+		if (options.Dirs || !entry.IsDir()) && fsMatch(options.Pattern, name) {
+			*result = append(*result, FileEntry{
+				DirEntry: entry,
+				Path:     name,
+				Depth:    depth,
+			})
+		}
+		// fs.WalkDir never walks into symbolic links, so linked directories are walked on their own
+		if linked, ok := entry.(fileInfoEntry); ok && entry.IsDir() && (options.MaxDepth == 0 || depth < options.MaxDepth) {
+			if linksToAncestor(fsys, name, linked.info) {
+				options.OnError.report(fmt.Errorf("symbolic link %s forms a cycle", name))
+				return nil
+			}
+			walkFS(fsys, name, depth, options, result)
+		}
+		return nil
+	})
+}
+
+// linksToAncestor tells whether a linked directory is one of the directories containing the link
+// Files are told apart by os.SameFile, so only links of the operating system's file system are detected
+func linksToAncestor(fsys fs.FS, name string, target fs.FileInfo) bool {
+	for dir := path.Dir(name); ; dir = path.Dir(dir) {
+		if info, err := fs.Stat(fsys, dir); err == nil && os.SameFile(info, target) {
+			return true
+		}
+		if dir == "." || dir == "/" {
+			return false
+		}
+	}
+}
+
+func fsDepth(root, name string) int {
+	if root == "." {
+		return strings.Count(name, "/") + 1
+	}
+	return strings.Count(name[len(root):], "/")
+}
+
+func fsMatch(pattern, name string) bool {
+	if pattern == "" {
+		return true
+	}
+	matched, _ := path.Match(pattern, path.Base(name))
+	return matched
+}
+
+// fileInfoEntry turns the information of a resolved symbolic link into a directory entry
+type fileInfoEntry struct {
+	info fs.FileInfo
+}
+
+func (e fileInfoEntry) Name() string {
+	return e.info.Name()
+}
+
+func (e fileInfoEntry) IsDir() bool {
+	return e.info.IsDir()
+}
+
+func (e fileInfoEntry) Type() fs.FileMode {
+	return e.info.Mode().Type()
+}
+
+func (e fileInfoEntry) Info() (fs.FileInfo, error) {
+	return e.info, nil
+}
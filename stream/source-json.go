@@ -0,0 +1,99 @@
+package stream
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+)
+
+// JSONOptions configures how FromJSONLines and FromJSONArray decode their input
+type JSONOptions struct {
+	// NewValue returns a pointer to decode each value into, values being decoded into generic ones when nil
+	NewValue func() interface{}
+	// OnError receives per-value errors, wrapped in a *RecordError
+	OnError ErrorHandler
+}
+
+// FromJSONLines returns a sequential stream decoding one JSON value per line of the input
+// Blank lines are ignored, and lines failing to be decoded are reported to options.OnError and skipped
+//
+// @param r			JSON Lines input, read when the stream is evaluated
+// @param options	Decoding options
+// @return			A sequential stream of decoded values
+func FromJSONLines(r io.Reader, options JSONOptions) Stream {
+	return fromSource(func() []interface{} {
+		reader := bufio.NewReader(r)
+		result := make([]interface{}, 0)
+		for index := 1; ; index++ {
+			line, err := reader.ReadBytes('\n')
+			if len(bytes.TrimSpace(line)) > 0 {
+				if value, decodeErr := decodeJSON(line, options.NewValue); decodeErr != nil {
+					options.OnError.report(&RecordError{Index: index, Err: decodeErr})
+				} else {
+					result = append(result, value)
+				}
+			}
+			if err != nil {
+				if err != io.EOF {
+					options.OnError.report(&RecordError{Index: index, Err: err})
+				}
+				return result
+			}
+		}
+	})
+}
+
+// FromJSONArray returns a sequential stream walking the elements of a top-level JSON array
+// Elements failing to be decoded into the value given by options.NewValue are reported to options.OnError and skipped,
+// while a syntax error stops the reading since the rest of the input can no longer be trusted
+//
+// @param r			JSON input, read when the stream is evaluated
+// @param options	Decoding options
+// @return			A sequential stream of decoded values
+func FromJSONArray(r io.Reader, options JSONOptions) Stream {
+	return fromSource(func() []interface{} {
+		decoder := json.NewDecoder(r)
+		result := make([]interface{}, 0)
+		token, err := decoder.Token()
+		if err != nil {
+			if err != io.EOF {
+				options.OnError.report(err)
+			}
+			return result
+		}
+		if delim, ok := token.(json.Delim); !ok || delim != '[' {
+			options.OnError.report(fmt.Errorf("expected a JSON array, found %v", token))
+			return result
+		}
+		for index := 1; decoder.More(); index++ {
+			var raw json.RawMessage
+			if err := decoder.Decode(&raw); err != nil {
+				options.OnError.report(&RecordError{Index: index, Err: err})
+				return result
+			}
+			value, err := decodeJSON(raw, options.NewValue)
+			if err != nil {
+				options.OnError.report(&RecordError{Index: index, Err: err})
+				continue
+			}
+			result = append(result, value)
+		}
+		if _, err := decoder.Token(); err != nil {
+			options.OnError.report(err)
+		}
+		return result
+	})
+}
+
+func decodeJSON(data []byte, newValue func() interface{}) (interface{}, error) {
+	if newValue == nil {
+		var value interface{}
+		err := json.Unmarshal(data, &value)
+		return value, err
+	}
+	value := newValue()
+	err := json.Unmarshal(data, value)
+	return value, err
+}
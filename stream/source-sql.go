@@ -0,0 +1,49 @@
+package stream
+
+import (
+	"context"
+	"database/sql"
+)
+
+// FromRows returns a sequential stream of the rows of a query result
+// The stream takes ownership of rows: they are scanned when the stream is evaluated, and closed once all of them are read
+// or as soon as ctx is done, whichever comes first, so cancel ctx to release rows of a stream which may never be evaluated
+// Rows failing to be scanned are reported to onError and skipped, and so is the error of ctx if it ends the scan early
+//
+// @param ctx		Context bounding the lifetime of rows, whose cancellation closes them
+// @param rows		Query result
+// @param scanFn	Function to scan the current row into a data item
+// @param onError	Handler of per-row errors, wrapped in a *RecordError
+// @return			A sequential stream of scanned rows
+func FromRows(ctx context.Context, rows *sql.Rows, scanFn func(*sql.Rows) (interface{}, error), onError ErrorHandler) Stream {
+	read := make(chan struct{})
+	// A context which is never done needs no watcher, which would otherwise leak along with an abandoned stream
+	if ctx.Done() != nil {
+		go func() {
+			select {
+			case <-ctx.Done():
+				rows.Close()
+			case <-read:
+			}
+		}()
+	}
+	return fromSource(func() []interface{} {
+		defer close(read)
+		defer rows.Close()
+		result := make([]interface{}, 0)
+		for index := 1; ctx.Err() == nil && rows.Next(); index++ {
+			item, err := scanFn(rows)
+			if err != nil {
+				onError.report(&RecordError{Index: index, Err: err})
+				continue
+			}
+			result = append(result, item)
+		}
+		if err := ctx.Err(); err != nil {
+			onError.report(err)
+		} else if err := rows.Err(); err != nil {
+			onError.report(err)
+		}
+		return result
+	})
+}
@@ -0,0 +1,54 @@
+package stream
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ErrorHandler receives errors met while reading records from an external source
+// A record failing to be read is skipped so that the rest of the source can still be processed
+type ErrorHandler func(err error)
+
+// RecordError describes a failure to read a single record from an external source
+type RecordError struct {
+	// Index is the 1-based position of the record in its source
+	Index int
+	Err   error
+}
+
+func (e *RecordError) Error() string {
+	return fmt.Sprintf("record %d: %v", e.Index, e.Err)
+}
+
+func (e *RecordError) Unwrap() error {
+	return e.Err
+}
+
+func (handler ErrorHandler) report(err error) {
+	if handler != nil {
+		handler(err)
+	}
+}
+
+// lazySource defers reading an external source until the stream is evaluated
+// Readers can only be consumed once, so the items read are cached and a copy is handed out on every call
+func lazySource(read func() []interface{}) func() []interface{} {
+	var once sync.Once
+	var items []interface{}
+	return func() []interface{} {
+		once.Do(func() {
+			items = read()
+		})
+		result := make([]interface{}, len(items))
+		copy(result, items)
+		return result
+	}
+}
+
+func fromSource(read func() []interface{}) Stream {
+	f := lazySource(read)
+	return &SequencialStream{
+		data:      f,
+		operation: f,
+	}
+}
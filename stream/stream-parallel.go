@@ -1,7 +1,11 @@
 package stream
 
 import (
+	"database/sql"
+	"io"
+	"math/rand"
 	"reflect"
+	"time"
 
 	"github.com/dynastywind/go-stream/stream/operation"
 	"github.com/dynastywind/go-stream/util"
@@ -12,6 +16,9 @@ type ParallelStream struct {
 	operation   func() []interface{}
 	descriptors []OperationDescriptor
 	routines    int
+	random      *rand.Rand
+	// unoptimized streams run operations as recorded, either because the optimizer is disabled or because they were built by it
+	unoptimized bool
 }
 
 // OfParallel returns a parallel stream from given data items
@@ -86,12 +93,35 @@ func ConcatAsParallel(routines int, s ...Stream) Stream {
 	}
 }
 
+func (s *ParallelStream) Apply(tag OperationTag, params ...interface{}) Stream {
+	op := mustBeRegistered(tag)
+	return &ParallelStream{
+		data: s.data,
+		operation: func() []interface{} {
+			if op.Parallel == nil {
+				return op.Sequential(s.ToArray(), params)
+			}
+			return op.Parallel(s.ToArray(), s.routines, params)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    tag,
+			params: params,
+		}),
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
 func (s *ParallelStream) AsParallel(routines int) Stream {
-	return s
+	if routines == s.routines {
+		return s
+	}
+	return Transform(parallelRoot(s.data, routines, s.random, s.unoptimized), s.descriptors)
 }
 
 func (s *ParallelStream) AsSequence() Stream {
-	return Transform(Of(s.data()...), s.descriptors)
+	return Transform(sequentialRoot(s.data, s.random, s.unoptimized), s.descriptors)
 }
 
 func (s *ParallelStream) AllMatch(predict func(interface{}) bool) bool {
@@ -135,63 +165,133 @@ func (s *ParallelStream) AnyMatch(predict func(interface{}) bool) bool {
 	return false
 }
 
-func (s *ParallelStream) Count() int {
-	return len(s.ToArray())
+func (s *ParallelStream) BottomK(k int, less func(interface{}, interface{}) bool) Stream {
+	return &ParallelStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.SmallestInParallel(s.ToArray(), s.routines, k, less)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    BOTTOM_K,
+			params: []interface{}{k, less},
+		}),
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
 }
 
-type HashPair struct {
-	hash string
-	item interface{}
+func (s *ParallelStream) Collect(collector Collector) interface{} {
+	return collector.Finish(operation.Collect(s.ToArray(), s.routines, collector.Supply, collector.Accumulate, collector.Combine))
+}
+
+func (s *ParallelStream) Count() int {
+	return len(s.ToArray())
 }
 
 func (s *ParallelStream) Distinct(hash func(interface{}) string) Stream {
 	return &ParallelStream{
 		data: s.data,
 		operation: func() []interface{} {
-			arr := s.ToArray()
-			length := len(arr)
-			ch := make(chan HashPair, length)
-			m := make(map[string]interface{})
-			var result []interface{}
-			for i, item := range arr {
-				go func(data interface{}) {
-					ch <- HashPair{
-						hash: hash(data),
-						item: data,
-					}
-				}(item)
-
-				if (i+1)%s.routines == 0 || i == length-1 {
-					for j := 0; j <= i%s.routines; j++ {
-						pair := <-ch
-						m[pair.hash] = pair.item
-					}
-				}
-			}
-			for _, v := range m {
-				result = append(result, v)
-			}
-			return result
+			return operation.DistinctInParallel(s.ToArray(), s.routines, func(item interface{}) interface{} {
+				return hash(item)
+			})
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    DISTINCT,
 			params: []interface{}{hash},
 		}),
-		routines: s.routines,
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *ParallelStream) DistinctApprox(hash func(interface{}) string, expectedN int, fpRate float64) Stream {
+	return &ParallelStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.DistinctApprox(s.ToArray(), s.routines, hash, expectedN, fpRate)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    DISTINCT_APPROX,
+			params: []interface{}{hash, expectedN, fpRate},
+		}),
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *ParallelStream) DistinctBy(key func(interface{}) interface{}) Stream {
+	return &ParallelStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.DistinctInParallel(s.ToArray(), s.routines, key)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    DISTINCT_BY,
+			params: []interface{}{key},
+		}),
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
+func (s *ParallelStream) DistinctWithin(hash func(interface{}) string, window int) Stream {
+	return &ParallelStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.DistinctWithin(s.ToArray(), s.routines, hash, window)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    DISTINCT_WITHIN,
+			params: []interface{}{hash, window},
+		}),
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *ParallelStream) DistinctWithinTime(hash func(interface{}) string, timestamp func(interface{}) time.Time, window time.Duration) Stream {
+	return &ParallelStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.DistinctWithinTime(s.ToArray(), s.routines, hash, timestamp, window)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    DISTINCT_WITHIN_TIME,
+			params: []interface{}{hash, timestamp, window},
+		}),
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *ParallelStream) Explain() *Plan {
+	descriptors := s.descriptors
+	if !s.unoptimized {
+		descriptors, _ = optimize(descriptors, true)
+	}
+	return explain(descriptors, true, s.routines)
+}
+
 func (s *ParallelStream) Filter(filter func(interface{}) bool) Stream {
 	return &ParallelStream{
 		data: s.data,
 		operation: func() []interface{} {
 			return operation.FilterInParallel(s.ToArray(), s.routines, filter, false)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    FILTER,
 			params: []interface{}{filter},
 		}),
-		routines: s.routines,
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -201,16 +301,18 @@ func (s *ParallelStream) FilterOrdered(filter func(interface{}) bool) Stream {
 		operation: func() []interface{} {
 			return operation.FilterInParallel(s.ToArray(), s.routines, filter, true)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    FILTER_ORDERED,
 			params: []interface{}{filter},
 		}),
-		routines: s.routines,
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
 func (s *ParallelStream) FindAny() *util.Optional {
-	return operation.FindAny(s.ToArray())
+	return operation.FindAny(s.ToArray(), s.random)
 }
 
 func (s *ParallelStream) FindFirst() *util.Optional {
@@ -223,11 +325,13 @@ func (s *ParallelStream) FlatMap(mapper func(interface{}) []interface{}) Stream
 		operation: func() []interface{} {
 			return operation.DoFlatMapInParallel(s.ToArray(), s.routines, mapper, false)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    FLAT_MAP,
 			params: []interface{}{mapper},
 		}),
-		routines: s.routines,
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -237,11 +341,13 @@ func (s *ParallelStream) FlatMapOrdered(mapper func(interface{}) []interface{})
 		operation: func() []interface{} {
 			return operation.DoFlatMapInParallel(s.ToArray(), s.routines, mapper, true)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    FLAT_MAP_ORDERED,
 			params: []interface{}{mapper},
 		}),
-		routines: s.routines,
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -259,11 +365,13 @@ func (s *ParallelStream) Limit(limit int) Stream {
 		operation: func() []interface{} {
 			return operation.Limit(s.ToArray(), limit)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    LIMIT,
 			params: []interface{}{limit},
 		}),
-		routines: s.routines,
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -273,11 +381,13 @@ func (s *ParallelStream) Map(mapper func(interface{}) interface{}) Stream {
 		operation: func() []interface{} {
 			return operation.DoMapInParallel(s.ToArray(), s.routines, mapper, false)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    MAP,
 			params: []interface{}{mapper},
 		}),
-		routines: s.routines,
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -287,20 +397,34 @@ func (s *ParallelStream) MapOrdered(mapper func(interface{}) interface{}) Stream
 		operation: func() []interface{} {
 			return operation.DoMapInParallel(s.ToArray(), s.routines, mapper, true)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    MAP_ORDERED,
 			params: []interface{}{mapper},
 		}),
-		routines: s.routines,
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
 func (s *ParallelStream) Max(less func(interface{}, interface{}) bool) *util.Optional {
-	return operation.MaxOrMin(s.ToArray(), less, true)
+	return operation.MaxOrMinInParallel(s.ToArray(), s.routines, less, true)
+}
+
+func (s *ParallelStream) MaxBy(key func(interface{}) interface{}) *util.Optional {
+	return s.Max(util.Comparing(key))
 }
 
 func (s *ParallelStream) Min(less func(interface{}, interface{}) bool) *util.Optional {
-	return operation.MaxOrMin(s.ToArray(), less, false)
+	return operation.MaxOrMinInParallel(s.ToArray(), s.routines, less, false)
+}
+
+func (s *ParallelStream) MinBy(key func(interface{}) interface{}) *util.Optional {
+	return s.Min(util.Comparing(key))
+}
+
+func (s *ParallelStream) MinMax(less func(interface{}, interface{}) bool) (*util.Optional, *util.Optional) {
+	return operation.MinMaxInParallel(s.ToArray(), s.routines, less)
 }
 
 func (s *ParallelStream) NoneMatch(predict func(interface{}) bool) bool {
@@ -330,11 +454,13 @@ func (s *ParallelStream) Peek(peeker func(interface{})) Stream {
 			operation.ForEachParallel(arr, s.routines, peeker)
 			return arr
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    PEEK,
 			params: []interface{}{peeker},
 		}),
-		routines: s.routines,
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -343,11 +469,19 @@ func (s *ParallelStream) Reduce(init interface{}, reducer func(interface{}, inte
 }
 
 func (s *ParallelStream) ReduceCombine(init interface{}, reducer func(interface{}, interface{}) interface{}, combiner func(interface{}, interface{}) interface{}) interface{} {
-	return operation.ReduceCombine(s.ToArray(), init, reducer, combiner)
+	return operation.ReduceCombineInParallel(s.ToArray(), s.routines, init, reducer, combiner, false)
+}
+
+func (s *ParallelStream) ReduceCombineOrdered(init interface{}, reducer func(interface{}, interface{}) interface{}, combiner func(interface{}, interface{}) interface{}) interface{} {
+	return operation.ReduceCombineInParallel(s.ToArray(), s.routines, init, reducer, combiner, true)
 }
 
 func (s *ParallelStream) ReduceOptional(reducer func(interface{}, interface{}) interface{}) *util.Optional {
-	return operation.ReduceOptional(s.ToArray(), reducer)
+	return operation.ReduceOptionalInParallel(s.ToArray(), s.routines, reducer, false)
+}
+
+func (s *ParallelStream) ReduceOptionalOrdered(reducer func(interface{}, interface{}) interface{}) *util.Optional {
+	return operation.ReduceOptionalInParallel(s.ToArray(), s.routines, reducer, true)
 }
 
 func (s *ParallelStream) Reverse() Stream {
@@ -371,10 +505,60 @@ func (s *ParallelStream) Reverse() Stream {
 			}
 			return arr
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag: REVERSE,
 		}),
-		routines: s.routines,
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *ParallelStream) Sample(k int) Stream {
+	return &ParallelStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.Sample(s.ToArray(), k, s.random)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    SAMPLE,
+			params: []interface{}{k},
+		}),
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *ParallelStream) SampleFraction(p float64) Stream {
+	return &ParallelStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.SampleFraction(s.ToArray(), p, s.random)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    SAMPLE_FRACTION,
+			params: []interface{}{p},
+		}),
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *ParallelStream) Shuffle() Stream {
+	return &ParallelStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.Shuffle(s.ToArray(), s.random)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    SHUFFLE,
+			params: []interface{}{},
+		}),
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -384,36 +568,68 @@ func (s *ParallelStream) Skip(skip int) Stream {
 		operation: func() []interface{} {
 			return operation.Skip(s.ToArray(), skip)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    SKIP,
 			params: []interface{}{skip},
 		}),
-		routines: s.routines,
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
 func (s *ParallelStream) Sorted(less func(interface{}, interface{}) bool) Stream {
+	return s.SortedWith(less, nil)
+}
+
+func (s *ParallelStream) SortedExternal(less func(interface{}, interface{}) bool, options util.ExternalSortOptions) Stream {
+	return s.SortedWith(less, util.ExternalSorter(options))
+}
+
+func (s *ParallelStream) SortedWith(less func(interface{}, interface{}) bool, sorter util.Sorter) Stream {
 	return &ParallelStream{
 		data: s.data,
 		operation: func() []interface{} {
-			return util.MergeSort(s.ToArray(), less)
+			if sorter == nil {
+				return util.ParallelMergeSort(s.ToArray(), less, s.routines)
+			}
+			return sorter.Sort(s.ToArray(), less)
 		},
-		routines: s.routines,
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    SORTED,
-			params: []interface{}{less},
+			params: []interface{}{less, sorter},
 		}),
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
 func (s *ParallelStream) ToArray() []interface{} {
+	if !s.unoptimized {
+		if descriptors, changed := optimize(s.descriptors, true); changed {
+			return Transform(s.root(), descriptors).ToArray()
+		}
+	}
 	return s.operation()
 }
 
+func (s *ParallelStream) ToCSV(w io.Writer, header []string, rowFn func(interface{}) []string) error {
+	return operation.ToCSV(s.ToArray(), w, header, rowFn)
+}
+
+func (s *ParallelStream) ToJSONLines(w io.Writer) error {
+	return operation.ToJSONLines(s.ToArray(), w)
+}
+
 func (s *ParallelStream) ToMap(keyMapper func(interface{}) interface{}, valueMapper func(interface{}) interface{}) map[interface{}]interface{} {
 	return operation.ToMap(s.ToArray(), keyMapper, valueMapper)
 }
 
+func (s *ParallelStream) ToSQLBatches(db *sql.DB, stmt string, batchSize int, argsFn func(interface{}) []interface{}) error {
+	return operation.ToSQLBatches(s.ToArray(), db, stmt, batchSize, argsFn)
+}
+
 func (s *ParallelStream) ToTypedArray(t reflect.Type) reflect.Value {
 	return operation.ToTypedArray(s.ToArray(), t)
 }
@@ -421,3 +637,57 @@ func (s *ParallelStream) ToTypedArray(t reflect.Type) reflect.Value {
 func (s *ParallelStream) ToTypedMap(t reflect.Type, keyMapper func(interface{}) interface{}, valueMapper func(interface{}) interface{}) reflect.Value {
 	return operation.ToTypedMap(s.ToArray(), t, keyMapper, valueMapper)
 }
+
+func (s *ParallelStream) TopK(k int, less func(interface{}, interface{}) bool) Stream {
+	return &ParallelStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.LargestInParallel(s.ToArray(), s.routines, k, less)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    TOP_K,
+			params: []interface{}{k, less},
+		}),
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *ParallelStream) Unoptimized() Stream {
+	return Transform(s.root(), s.descriptors)
+}
+
+func (s *ParallelStream) WithRandom(random *rand.Rand) Stream {
+	// Operations recorded so far are added again, so that they draw from the new source as well
+	return Transform(parallelRoot(s.data, s.routines, random, s.unoptimized), s.descriptors)
+}
+
+// root returns a stream of the source items, running operations added onto it as recorded
+func (s *ParallelStream) root() *ParallelStream {
+	return &ParallelStream{
+		data:        s.data,
+		operation:   s.data,
+		routines:    s.routines,
+		random:      s.random,
+		unoptimized: true,
+	}
+}
+
+// parallelRoot returns a parallel stream of the items read by data, which are copied so that operations working in place leave them intact
+func parallelRoot(data func() []interface{}, routines int, random *rand.Rand, unoptimized bool) *ParallelStream {
+	if routines < 1 {
+		panic("Parallel version need go routines greater than 1. Otherwise please use sequential version for better performance.")
+	}
+	f := func() []interface{} {
+		var result []interface{}
+		return append(result, data()...)
+	}
+	return &ParallelStream{
+		data:        f,
+		operation:   f,
+		routines:    routines,
+		random:      random,
+		unoptimized: unoptimized,
+	}
+}
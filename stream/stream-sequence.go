@@ -1,9 +1,12 @@
 package stream
 
 import (
+	"database/sql"
+	"io"
+	"math/rand"
 	"reflect"
+	"time"
 
-	"github.com/Workiva/go-datastructures/set"
 	"github.com/dynastywind/go-stream/stream/operation"
 	"github.com/dynastywind/go-stream/util"
 )
@@ -12,6 +15,9 @@ type SequencialStream struct {
 	data        func() []interface{}
 	operation   func() []interface{}
 	descriptors []OperationDescriptor
+	random      *rand.Rand
+	// unoptimized streams run operations as recorded, either because the optimizer is disabled or because they were built by it
+	unoptimized bool
 }
 
 // Of returns a sequential stream from given data items
@@ -76,8 +82,24 @@ func Concat(s ...Stream) Stream {
 	}
 }
 
+func (s *SequencialStream) Apply(tag OperationTag, params ...interface{}) Stream {
+	op := mustBeRegistered(tag)
+	return &SequencialStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return op.Sequential(s.ToArray(), params)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    tag,
+			params: params,
+		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
 func (s *SequencialStream) AsParallel(routines int) Stream {
-	return Transform(OfParallel(routines, s.data()...), s.descriptors)
+	return Transform(parallelRoot(s.data, routines, s.random, s.unoptimized), s.descriptors)
 }
 
 func (s *SequencialStream) AsSequence() Stream {
@@ -106,6 +128,25 @@ func (s *SequencialStream) AnyMatch(predict func(interface{}) bool) bool {
 	return false
 }
 
+func (s *SequencialStream) BottomK(k int, less func(interface{}, interface{}) bool) Stream {
+	return &SequencialStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.Smallest(s.ToArray(), k, less)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    BOTTOM_K,
+			params: []interface{}{k, less},
+		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *SequencialStream) Collect(collector Collector) interface{} {
+	return collector.Finish(operation.Collect(s.ToArray(), 1, collector.Supply, collector.Accumulate, collector.Combine))
+}
+
 func (s *SequencialStream) Count() int {
 	return len(s.ToArray())
 }
@@ -114,34 +155,99 @@ func (s *SequencialStream) Distinct(hash func(interface{}) string) Stream {
 	return &SequencialStream{
 		data: s.data,
 		operation: func() []interface{} {
-			set := set.New()
-			var result []interface{}
-			for _, item := range s.ToArray() {
-				id := hash(item)
-				if !set.Exists(id) {
-					set.Add(id)
-					result = append(result, item)
-				}
-			}
-			return result
+			return operation.Distinct(s.ToArray(), func(item interface{}) interface{} {
+				return hash(item)
+			})
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    DISTINCT,
 			params: []interface{}{hash},
 		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *SequencialStream) DistinctApprox(hash func(interface{}) string, expectedN int, fpRate float64) Stream {
+	return &SequencialStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.DistinctApprox(s.ToArray(), 1, hash, expectedN, fpRate)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    DISTINCT_APPROX,
+			params: []interface{}{hash, expectedN, fpRate},
+		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *SequencialStream) DistinctBy(key func(interface{}) interface{}) Stream {
+	return &SequencialStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.Distinct(s.ToArray(), key)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    DISTINCT_BY,
+			params: []interface{}{key},
+		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *SequencialStream) DistinctWithin(hash func(interface{}) string, window int) Stream {
+	return &SequencialStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.DistinctWithin(s.ToArray(), 1, hash, window)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    DISTINCT_WITHIN,
+			params: []interface{}{hash, window},
+		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
+func (s *SequencialStream) DistinctWithinTime(hash func(interface{}) string, timestamp func(interface{}) time.Time, window time.Duration) Stream {
+	return &SequencialStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.DistinctWithinTime(s.ToArray(), 1, hash, timestamp, window)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    DISTINCT_WITHIN_TIME,
+			params: []interface{}{hash, timestamp, window},
+		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *SequencialStream) Explain() *Plan {
+	descriptors := s.descriptors
+	if !s.unoptimized {
+		descriptors, _ = optimize(descriptors, false)
+	}
+	return explain(descriptors, false, 1)
+}
+
 func (s *SequencialStream) Filter(filter func(interface{}) bool) Stream {
 	return &SequencialStream{
 		data: s.data,
 		operation: func() []interface{} {
 			return operation.Filter(s.ToArray(), filter)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    FILTER,
 			params: []interface{}{filter},
 		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -151,15 +257,17 @@ func (s *SequencialStream) FilterOrdered(filter func(interface{}) bool) Stream {
 		operation: func() []interface{} {
 			return operation.Filter(s.ToArray(), filter)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    FILTER_ORDERED,
 			params: []interface{}{filter},
 		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
 func (s *SequencialStream) FindAny() *util.Optional {
-	return operation.FindAny(s.ToArray())
+	return operation.FindAny(s.ToArray(), s.random)
 }
 
 func (s *SequencialStream) FindFirst() *util.Optional {
@@ -172,10 +280,12 @@ func (s *SequencialStream) FlatMap(mapper func(interface{}) []interface{}) Strea
 		operation: func() []interface{} {
 			return operation.DoFlatMap(s.ToArray(), mapper)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    FLAT_MAP,
 			params: []interface{}{mapper},
 		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -185,10 +295,12 @@ func (s *SequencialStream) FlatMapOrdered(mapper func(interface{}) []interface{}
 		operation: func() []interface{} {
 			return operation.DoFlatMap(s.ToArray(), mapper)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    FLAT_MAP_ORDERED,
 			params: []interface{}{mapper},
 		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -208,10 +320,12 @@ func (s *SequencialStream) Limit(limit int) Stream {
 		operation: func() []interface{} {
 			return operation.Limit(s.ToArray(), limit)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    LIMIT,
 			params: []interface{}{limit},
 		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -221,10 +335,12 @@ func (s *SequencialStream) Map(mapper func(interface{}) interface{}) Stream {
 		operation: func() []interface{} {
 			return operation.DoMap(s.ToArray(), mapper)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    MAP,
 			params: []interface{}{mapper},
 		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -234,10 +350,12 @@ func (s *SequencialStream) MapOrdered(mapper func(interface{}) interface{}) Stre
 		operation: func() []interface{} {
 			return operation.DoMap(s.ToArray(), mapper)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    MAP_ORDERED,
 			params: []interface{}{mapper},
 		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -245,10 +363,22 @@ func (s *SequencialStream) Max(less func(interface{}, interface{}) bool) *util.O
 	return operation.MaxOrMin(s.ToArray(), less, true)
 }
 
+func (s *SequencialStream) MaxBy(key func(interface{}) interface{}) *util.Optional {
+	return s.Max(util.Comparing(key))
+}
+
 func (s *SequencialStream) Min(less func(interface{}, interface{}) bool) *util.Optional {
 	return operation.MaxOrMin(s.ToArray(), less, false)
 }
 
+func (s *SequencialStream) MinBy(key func(interface{}) interface{}) *util.Optional {
+	return s.Min(util.Comparing(key))
+}
+
+func (s *SequencialStream) MinMax(less func(interface{}, interface{}) bool) (*util.Optional, *util.Optional) {
+	return operation.MinMax(s.ToArray(), less)
+}
+
 func (s *SequencialStream) NoneMatch(predict func(interface{}) bool) bool {
 	for _, item := range s.ToArray() {
 		if predict(item) {
@@ -268,10 +398,12 @@ func (s *SequencialStream) Peek(peeker func(interface{})) Stream {
 			}
 			return arr
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    PEEK,
 			params: []interface{}{peeker},
 		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -287,6 +419,14 @@ func (s *SequencialStream) ReduceCombine(init interface{}, reducer func(interfac
 	return operation.ReduceCombine(s.ToArray(), init, reducer, combiner)
 }
 
+func (s *SequencialStream) ReduceCombineOrdered(init interface{}, reducer func(interface{}, interface{}) interface{}, combiner func(interface{}, interface{}) interface{}) interface{} {
+	return s.ReduceCombine(init, reducer, combiner)
+}
+
+func (s *SequencialStream) ReduceOptionalOrdered(reducer func(interface{}, interface{}) interface{}) *util.Optional {
+	return s.ReduceOptional(reducer)
+}
+
 func (s *SequencialStream) Reverse() Stream {
 	return &SequencialStream{
 		data: s.data,
@@ -298,9 +438,56 @@ func (s *SequencialStream) Reverse() Stream {
 			}
 			return arr
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag: REVERSE,
 		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *SequencialStream) Sample(k int) Stream {
+	return &SequencialStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.Sample(s.ToArray(), k, s.random)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    SAMPLE,
+			params: []interface{}{k},
+		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *SequencialStream) SampleFraction(p float64) Stream {
+	return &SequencialStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.SampleFraction(s.ToArray(), p, s.random)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    SAMPLE_FRACTION,
+			params: []interface{}{p},
+		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *SequencialStream) Shuffle() Stream {
+	return &SequencialStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.Shuffle(s.ToArray(), s.random)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    SHUFFLE,
+			params: []interface{}{},
+		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
@@ -310,34 +497,66 @@ func (s *SequencialStream) Skip(skip int) Stream {
 		operation: func() []interface{} {
 			return operation.Skip(s.ToArray(), skip)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    SKIP,
 			params: []interface{}{skip},
 		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
 func (s *SequencialStream) Sorted(less func(prev, next interface{}) bool) Stream {
+	return s.SortedWith(less, nil)
+}
+
+func (s *SequencialStream) SortedExternal(less func(interface{}, interface{}) bool, options util.ExternalSortOptions) Stream {
+	return s.SortedWith(less, util.ExternalSorter(options))
+}
+
+func (s *SequencialStream) SortedWith(less func(prev, next interface{}) bool, sorter util.Sorter) Stream {
 	return &SequencialStream{
 		data: s.data,
 		operation: func() []interface{} {
-			return util.HeapSort(s.ToArray(), less)
+			if sorter == nil {
+				return util.MergeSorter.Sort(s.ToArray(), less)
+			}
+			return sorter.Sort(s.ToArray(), less)
 		},
-		descriptors: append(s.descriptors, OperationDescriptor{
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
 			tag:    SORTED,
-			params: []interface{}{less},
+			params: []interface{}{less, sorter},
 		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
 	}
 }
 
 func (s *SequencialStream) ToArray() []interface{} {
+	if !s.unoptimized {
+		if descriptors, changed := optimize(s.descriptors, false); changed {
+			return Transform(s.root(), descriptors).ToArray()
+		}
+	}
 	return s.operation()
 }
 
+func (s *SequencialStream) ToCSV(w io.Writer, header []string, rowFn func(interface{}) []string) error {
+	return operation.ToCSV(s.ToArray(), w, header, rowFn)
+}
+
+func (s *SequencialStream) ToJSONLines(w io.Writer) error {
+	return operation.ToJSONLines(s.ToArray(), w)
+}
+
 func (s *SequencialStream) ToMap(keyMapper func(interface{}) interface{}, valueMapper func(interface{}) interface{}) map[interface{}]interface{} {
 	return operation.ToMap(s.ToArray(), keyMapper, valueMapper)
 }
 
+func (s *SequencialStream) ToSQLBatches(db *sql.DB, stmt string, batchSize int, argsFn func(interface{}) []interface{}) error {
+	return operation.ToSQLBatches(s.ToArray(), db, stmt, batchSize, argsFn)
+}
+
 func (s *SequencialStream) ToTypedArray(t reflect.Type) reflect.Value {
 	return operation.ToTypedArray(s.ToArray(), t)
 }
@@ -345,3 +564,51 @@ func (s *SequencialStream) ToTypedArray(t reflect.Type) reflect.Value {
 func (s *SequencialStream) ToTypedMap(t reflect.Type, keyMapper func(interface{}) interface{}, valueMapper func(interface{}) interface{}) reflect.Value {
 	return operation.ToTypedMap(s.ToArray(), t, keyMapper, valueMapper)
 }
+
+func (s *SequencialStream) TopK(k int, less func(interface{}, interface{}) bool) Stream {
+	return &SequencialStream{
+		data: s.data,
+		operation: func() []interface{} {
+			return operation.Largest(s.ToArray(), k, less)
+		},
+		descriptors: withDescriptor(s.descriptors, OperationDescriptor{
+			tag:    TOP_K,
+			params: []interface{}{k, less},
+		}),
+		random:      s.random,
+		unoptimized: s.unoptimized,
+	}
+}
+
+func (s *SequencialStream) Unoptimized() Stream {
+	return Transform(s.root(), s.descriptors)
+}
+
+func (s *SequencialStream) WithRandom(random *rand.Rand) Stream {
+	// Operations recorded so far are added again, so that they draw from the new source as well
+	return Transform(sequentialRoot(s.data, random, s.unoptimized), s.descriptors)
+}
+
+// root returns a stream of the source items, running operations added onto it as recorded
+func (s *SequencialStream) root() *SequencialStream {
+	return &SequencialStream{
+		data:        s.data,
+		operation:   s.data,
+		random:      s.random,
+		unoptimized: true,
+	}
+}
+
+// sequentialRoot returns a sequential stream of the items read by data, which are copied so that operations working in place leave them intact
+func sequentialRoot(data func() []interface{}, random *rand.Rand, unoptimized bool) *SequencialStream {
+	f := func() []interface{} {
+		var result []interface{}
+		return append(result, data()...)
+	}
+	return &SequencialStream{
+		data:        f,
+		operation:   f,
+		random:      random,
+		unoptimized: unoptimized,
+	}
+}
@@ -1,19 +1,32 @@
 package stream
 
 import (
+	"database/sql"
+	"io"
+	"math/rand"
 	"reflect"
+	"time"
 
 	"github.com/dynastywind/go-stream/util"
 )
 
 type Stream interface {
-	// AsParallel returns a parallel stream
+	// Apply adds a registered user-defined operation onto the data stream
+	// It panics if no operation is registered with this tag
+	//
+	// @param tag		Tag the operation is registered with
+	// @param params	Parameters handed to the operation's implementation
+	// @return			A stream after applying the operation
+	Apply(tag OperationTag, params ...interface{}) Stream
+
+	// AsParallel returns a parallel stream running every operation of the pipeline, including those added before, with the given number of go routines
+	// A parallel stream already using this number of go routines returns itself
 	//
 	// @param	routines	Number of go routines to use
 	// @return	A parallel stream with the same pipeline as original stream
 	AsParallel(routines int) Stream
 
-	// AsSequence returns a sequential stream
+	// AsSequence returns a sequential stream running every operation of the pipeline, including those added before
 	//
 	// @return	A sequential stream with the same pipeline as original stream
 	AsSequence() Stream
@@ -30,17 +43,68 @@ type Stream interface {
 	// @return			True if any data items matches condition, false otherwise
 	AnyMatch(predict func(interface{}) bool) bool
 
+	// BottomK returns a stream of the k smallest items in ascending order, equal items keeping their original order
+	// It keeps a bounded heap rather than sorting the whole stream
+	//
+	// @param k		Number of items to keep
+	// @param less	Function to judge which value is smaller
+	// @return		A stream with at most k items
+	BottomK(k int, less func(interface{}, interface{}) bool) Stream
+
+	// Collect runs a mutable reduction over the data stream
+	// A parallel stream accumulates each routine's chunk of items in its own container, then combines containers in encounter order
+	//
+	// @param collector	Collector describing the reduction
+	// @return			Finished result of the collector
+	Collect(collector Collector) interface{}
+
 	// Count returns total number of items in data stream
 	//
 	// @return	Number of items in data stream
 	Count() int
 
-	// Distinct returns a data stream containing unique items
+	// Distinct returns a data stream containing unique items, keeping the first occurrence of each in encounter order
 	//
 	// @param hash	Function to generate data item's identity
 	// @return		A data stream with unique items
 	Distinct(hash func(interface{}) string) Stream
 
+	// DistinctBy does the same thing as Distinct but identifies data items with a key of any comparable type
+	//
+	// @param key	Function to generate data item's identity, which should be comparable
+	// @return		A data stream with unique items
+	DistinctBy(key func(interface{}) interface{}) Stream
+
+	// DistinctApprox does the same thing as Distinct within bounded memory, remembering hashes in a Bloom filter
+	// Some unique items may be dropped as false positives, so only use it where that is acceptable
+	//
+	// @param hash		Function to generate data item's identity
+	// @param expectedN	Expected number of unique items
+	// @param fpRate	Rate of unique items wrongly dropped once expectedN of them are seen
+	// @return			A data stream with unique items
+	DistinctApprox(hash func(interface{}) string, expectedN int, fpRate float64) Stream
+
+	// DistinctWithin drops data items whose identity is among the last window ones seen, so that memory stays bounded
+	//
+	// @param hash		Function to generate data item's identity
+	// @param window	Number of identities to remember
+	// @return			A data stream without duplicates close to each other
+	DistinctWithin(hash func(interface{}) string, window int) Stream
+
+	// DistinctWithinTime drops data items whose identity was seen less than a duration before their timestamp
+	// Timestamps are expected to be roughly increasing
+	//
+	// @param hash		Function to generate data item's identity
+	// @param timestamp	Function to get the time of a data item
+	// @param window	Duration during which an identity is remembered
+	// @return			A data stream without duplicates close in time
+	DistinctWithinTime(hash func(interface{}) string, timestamp func(interface{}) time.Time, window time.Duration) Stream
+
+	// Explain describes the pipeline of this stream without evaluating it
+	//
+	// @return	A plan listing intermediate operations, which can be printed or rendered as a Graphviz graph
+	Explain() *Plan
+
 	// Filter returns a new stream containing only items matching filter condition
 	// This method does not guarantee the processing order
 	//
@@ -55,6 +119,7 @@ type Stream interface {
 	FilterOrdered(filter func(interface{}) bool) Stream
 
 	// FindAny randomly returns an item in the data stream if exists
+	// The item is drawn from the stream's random source, see WithRandom
 	//
 	// @return	Any item in data stream
 	FindAny() *util.Optional
@@ -106,18 +171,36 @@ type Stream interface {
 	// @return			A stream after applying map operation
 	MapOrdered(mapper func(interface{}) interface{}) Stream
 
-	// Max returns the maximum value in the data stream
+	// Max returns the maximum value in the data stream, the first one if several are equal
 	//
 	// @param less	Function to judge which value is smaller
 	// @return		Maximum value in the data stream
 	Max(less func(interface{}, interface{}) bool) *util.Optional
 
-	// Min returns the minimal value in the data stream
+	// MaxBy returns the value with the maximum key in the data stream, keys being compared by their natural order
+	//
+	// @param key	Function to extract the key of a data item
+	// @return		Value with the maximum key in the data stream
+	MaxBy(key func(interface{}) interface{}) *util.Optional
+
+	// Min returns the minimal value in the data stream, the first one if several are equal
 	//
 	// @param less	Function to judge which value is smaller
 	// @return		Minimal value in the data stream
 	Min(less func(interface{}, interface{}) bool) *util.Optional
 
+	// MinBy returns the value with the minimal key in the data stream, keys being compared by their natural order
+	//
+	// @param key	Function to extract the key of a data item
+	// @return		Value with the minimal key in the data stream
+	MinBy(key func(interface{}) interface{}) *util.Optional
+
+	// MinMax returns both the minimal and the maximum values in the data stream in a single pass
+	//
+	// @param less	Function to judge which value is smaller
+	// @return		Minimal and maximum values in the data stream
+	MinMax(less func(interface{}, interface{}) bool) (*util.Optional, *util.Optional)
+
 	// NoneMatch returns true if none of the items in the data stream matches the given condition
 	//
 	// @param predict	Prediction function
@@ -131,31 +214,71 @@ type Stream interface {
 	Peek(peeker func(interface{})) Stream
 
 	// Reduce returns a single value after accumulatively merge every data item in the stream
+	// Items are merged one by one in encounter order, even in a parallel stream, as reducer cannot merge two partial results
+	// Use ReduceCombine to merge chunks of a parallel stream in parallel
 	//
 	// @param init		Initial value to be accumulated
 	// @param reducer	Function to merge elements
 	// @return			A merged result
 	Reduce(init interface{}, reducer func(interface{}, interface{}) interface{}) interface{}
 
-	// ReduceCombine returns a single value after accumulatively merge and combine every data item in the stream
+	// ReduceCombine returns a single value after accumulatively merge every data item in the stream
+	// A parallel stream reduces each routine's chunk of items from init, then merges partial results with combiner in any order
+	// So init should be an identity of combiner, and combiner should be associative and commutative
 	//
 	// @param init		Initial value to be accumulated
-	// @param reducer	Function to merge elements
-	// @param combiner	Function to combine merged result and other value
+	// @param reducer	Function to merge an element into an accumulated value
+	// @param combiner	Function to merge two accumulated values
 	// @return			A merged result
 	ReduceCombine(init interface{}, reducer func(interface{}, interface{}) interface{}, combiner func(interface{}, interface{}) interface{}) interface{}
 
+	// ReduceCombineOrdered does the same thing as ReduceCombine, but a parallel stream merges partial results of adjacent chunks in a tree
+	// So combiner needs not be commutative
+	//
+	// @param init		Initial value to be accumulated
+	// @param reducer	Function to merge an element into an accumulated value
+	// @param combiner	Function to merge two accumulated values
+	// @return			A merged result
+	ReduceCombineOrdered(init interface{}, reducer func(interface{}, interface{}) interface{}, combiner func(interface{}, interface{}) interface{}) interface{}
+
 	// ReduceOptional returns a single value after accumulatively merge every data item in the stream if any item exists, empty otherwise
+	// A parallel stream reduces each routine's chunk of items, then merges partial results with reducer in any order
+	// So reducer should be associative and commutative
 	//
 	// @param reducer	Function to merge elements
 	// @return			A merged result or empty
 	ReduceOptional(reducer func(interface{}, interface{}) interface{}) *util.Optional
 
+	// ReduceOptionalOrdered does the same thing as ReduceOptional, but a parallel stream merges partial results of adjacent chunks in a tree
+	// So reducer needs not be commutative
+	//
+	// @param reducer	Function to merge elements
+	// @return			A merged result or empty
+	ReduceOptionalOrdered(reducer func(interface{}, interface{}) interface{}) *util.Optional
+
 	// Reverse completely reverse the current order of data items in this stream and return a new stream
 	//
 	// @return	A stream with items' order reversed in original stream
 	Reverse() Stream
 
+	// Sample returns a stream of k data items chosen uniformly at random in a single pass, keeping their encounter order
+	// If k is greater than current stream length, every item is kept
+	//
+	// @param k	Number of items to keep
+	// @return	A stream with at most k items
+	Sample(k int) Stream
+
+	// SampleFraction returns a stream keeping every data item independently with probability p, in encounter order
+	//
+	// @param p	Probability to keep a data item, between 0 and 1
+	// @return	A stream with sampled items
+	SampleFraction(p float64) Stream
+
+	// Shuffle returns a stream with data items in a uniformly random order
+	//
+	// @return	A stream with items shuffled
+	Shuffle() Stream
+
 	// Skip throws the first N items away in the data stream and returns the new stream
 	// If N is greater than current stream length, an empty stream is returned
 	//
@@ -169,11 +292,40 @@ type Stream interface {
 	// @return		A stream with data items sorted in ascending order
 	Sorted(less func(interface{}, interface{}) bool) Stream
 
+	// SortedExternal does the same thing as Sorted, but spills sorted runs to temporary files once the stream holds more than options.RunSize items
+	// Runs are merged back lazily, and errors met writing or reading them are reported to options.OnError, items being sorted in memory instead
+	//
+	// @param less		Function to judge which value is smaller
+	// @param options	External sort options, whose RunSize is the number of items above which runs are spilled
+	// @return			A stream with data items sorted in ascending order
+	SortedExternal(less func(interface{}, interface{}) bool, options util.ExternalSortOptions) Stream
+
+	// SortedWith does the same thing as Sorted but with a given sorting algorithm
+	//
+	// @param less		Function to judge which value is smaller
+	// @param sorter	Sorting algorithm, nil to use the default one of this stream
+	// @return			A stream with data items sorted in ascending order
+	SortedWith(less func(interface{}, interface{}) bool, sorter util.Sorter) Stream
+
 	// ToArray collects data from this stream into an array
 	//
 	// @return	An array whose data is generated from this stream
 	ToArray() []interface{}
 
+	// ToCSV writes data items in this stream as CSV records
+	//
+	// @param w			Destination of CSV records
+	// @param header	Column names written as the first record, skipped if nil
+	// @param rowFn		Function to map a data item to the fields of a record
+	// @return			Error met while writing, nil otherwise
+	ToCSV(w io.Writer, header []string, rowFn func(interface{}) []string) error
+
+	// ToJSONLines writes data items in this stream as JSON values, one per line
+	//
+	// @param w	Destination of JSON lines
+	// @return	Error met while encoding or writing, nil otherwise
+	ToJSONLines(w io.Writer) error
+
 	// ToMap collects data from this stream and transform to a map
 	//
 	// @param keyMapper		Function to map data item to map key
@@ -181,10 +333,22 @@ type Stream interface {
 	// @return				A map whose data is generated from this stream
 	ToMap(keyMapper func(interface{}) interface{}, valueMapper func(interface{}) interface{}) map[interface{}]interface{}
 
+	// ToSQLBatches writes data items in this stream into a database, one transaction per batch
+	// Writing stops at the first failing batch, whose transaction is rolled back while former batches stay committed
+	//
+	// @param db		Database to write into
+	// @param stmt		Statement executed once per data item
+	// @param batchSize	Number of data items written in a transaction
+	// @param argsFn	Function to map a data item to the arguments of the statement
+	// @return			Error met while writing, nil otherwise
+	ToSQLBatches(db *sql.DB, stmt string, batchSize int, argsFn func(interface{}) []interface{}) error
+
 	// ToTypedArray does the same thing as ToArray method but will transform the result into a typed one via reflection
 	//
 	// @param t	Type of array element
 	// @return	Typed array containing stream processing result
+	//
+	// Deprecated: use CollectInto, which reports mistyped items as errors and does not reflect on every item
 	ToTypedArray(t reflect.Type) reflect.Value
 
 	// ToTypedMap does the same thing as ToMap method but will transform the result into a typed key-value pair via reflection
@@ -193,5 +357,31 @@ type Stream interface {
 	// @param keyMapper		Function to map data item to map key
 	// @param valueMapper	Function to map data item to map value
 	// @return				Typed map containing stream processing result
+	//
+	// Deprecated: use CollectIntoMap, which reports mistyped keys and values as errors and does not reflect on every item
 	ToTypedMap(t reflect.Type, keyMapper func(interface{}) interface{}, valueMapper func(interface{}) interface{}) reflect.Value
+
+	// TopK returns a stream of the k largest items in descending order, equal items keeping their original order
+	// It keeps a bounded heap rather than sorting the whole stream
+	//
+	// @param k		Number of items to keep
+	// @param less	Function to judge which value is smaller
+	// @return		A stream with at most k items
+	TopK(k int, less func(interface{}, interface{}) bool) Stream
+
+	// Unoptimized returns a stream running its operations as recorded
+	// Otherwise, a pipeline is rewritten into an equivalent one doing less work when evaluated, like fusing adjacent maps
+	// Use it when operations have side effects which should not be skipped or merged
+	//
+	// @return	A stream with the same pipeline, which is not optimized
+	Unoptimized() Stream
+
+	// WithRandom returns a stream drawing from the given random source in FindAny, sampling and shuffling, so that results are reproducible
+	// The source applies to the whole stream, including operations added before this call, and is not a stage of the pipeline
+	// The source is not safe for concurrent use, so it should not be shared with streams evaluated at the same time
+	// Without it, the global source of math/rand is used
+	//
+	// @param random	Random source to use
+	// @return			A stream with the same items using the given random source
+	WithRandom(random *rand.Rand) Stream
 }
@@ -3,31 +3,62 @@ package stream
 import (
 	"fmt"
 	"reflect"
+	"time"
+
+	"github.com/dynastywind/go-stream/util"
 )
 
 func Transform(stream Stream, descriptors []OperationDescriptor) Stream {
 	for _, desc := range descriptors {
 		switch desc.tag {
+		case BOTTOM_K:
+			stream = stream.BottomK(desc.params[0].(int), desc.params[1].(func(interface{}, interface{}) bool))
 		case DISTINCT:
 			stream = stream.Distinct(desc.params[0].(func(interface{}) string))
+		case DISTINCT_APPROX:
+			stream = stream.DistinctApprox(desc.params[0].(func(interface{}) string), desc.params[1].(int), desc.params[2].(float64))
+		case DISTINCT_BY:
+			stream = stream.DistinctBy(desc.params[0].(func(interface{}) interface{}))
+		case DISTINCT_WITHIN:
+			stream = stream.DistinctWithin(desc.params[0].(func(interface{}) string), desc.params[1].(int))
+		case DISTINCT_WITHIN_TIME:
+			stream = stream.DistinctWithinTime(desc.params[0].(func(interface{}) string), desc.params[1].(func(interface{}) time.Time), desc.params[2].(time.Duration))
 		case FILTER:
 			stream = stream.Filter(desc.params[0].(func(interface{}) bool))
+		case FILTER_ORDERED:
+			stream = stream.FilterOrdered(desc.params[0].(func(interface{}) bool))
 		case FLAT_MAP:
 			stream = stream.FlatMap(desc.params[0].(func(interface{}) []interface{}))
+		case FLAT_MAP_ORDERED:
+			stream = stream.FlatMapOrdered(desc.params[0].(func(interface{}) []interface{}))
 		case LIMIT:
 			stream = stream.Limit(desc.params[0].(int))
 		case MAP:
 			stream = stream.Map(desc.params[0].(func(interface{}) interface{}))
+		case MAP_ORDERED:
+			stream = stream.MapOrdered(desc.params[0].(func(interface{}) interface{}))
 		case PEEK:
 			stream = stream.Peek(desc.params[0].(func(interface{})))
 		case REVERSE:
 			stream = stream.Reverse()
+		case SAMPLE:
+			stream = stream.Sample(desc.params[0].(int))
+		case SAMPLE_FRACTION:
+			stream = stream.SampleFraction(desc.params[0].(float64))
+		case SHUFFLE:
+			stream = stream.Shuffle()
 		case SKIP:
 			stream = stream.Skip(desc.params[0].(int))
 		case SORTED:
-			stream = stream.Sorted(desc.params[0].(func(interface{}, interface{}) bool))
+			sorter, _ := desc.params[1].(util.Sorter)
+			stream = stream.SortedWith(desc.params[0].(func(interface{}, interface{}) bool), sorter)
+		case TOP_K:
+			stream = stream.TopK(desc.params[0].(int), desc.params[1].(func(interface{}, interface{}) bool))
 		default:
-			panic(fmt.Sprintf("Unsupported operation type found: %v", desc.tag))
+			if _, ok := registered(desc.tag); !ok {
+				panic(fmt.Sprintf("Unsupported operation type found: %v", desc.tag))
+			}
+			stream = stream.Apply(desc.tag, desc.params...)
 		}
 	}
 	return stream
@@ -1,23 +1,59 @@
 package stream
 
+import "sort"
+
 type OperationTag string
 
 const (
-	DISTINCT         OperationTag = "DISTINCT"
-	FILTER           OperationTag = "FILTER"
-	FILTER_ORDERED   OperationTag = "FILTER_ORDERED"
-	FLAT_MAP         OperationTag = "FLATMAP"
-	FLAT_MAP_ORDERED OperationTag = "FLAT_MAP_ORDERED"
-	LIMIT            OperationTag = "LIMIT"
-	MAP              OperationTag = "MAP"
-	MAP_ORDERED      OperationTag = "MAP_ORDERED"
-	PEEK             OperationTag = "PEEK"
-	REVERSE          OperationTag = "REVERSE"
-	SKIP             OperationTag = "SKIP"
-	SORTED           OperationTag = "SORTED"
+	BOTTOM_K             OperationTag = "BOTTOM_K"
+	DISTINCT             OperationTag = "DISTINCT"
+	DISTINCT_APPROX      OperationTag = "DISTINCT_APPROX"
+	DISTINCT_BY          OperationTag = "DISTINCT_BY"
+	DISTINCT_WITHIN      OperationTag = "DISTINCT_WITHIN"
+	DISTINCT_WITHIN_TIME OperationTag = "DISTINCT_WITHIN_TIME"
+	FILTER               OperationTag = "FILTER"
+	FILTER_ORDERED       OperationTag = "FILTER_ORDERED"
+	FLAT_MAP             OperationTag = "FLATMAP"
+	FLAT_MAP_ORDERED     OperationTag = "FLAT_MAP_ORDERED"
+	LIMIT                OperationTag = "LIMIT"
+	MAP                  OperationTag = "MAP"
+	MAP_ORDERED          OperationTag = "MAP_ORDERED"
+	PEEK                 OperationTag = "PEEK"
+	REVERSE              OperationTag = "REVERSE"
+	SAMPLE               OperationTag = "SAMPLE"
+	SAMPLE_FRACTION      OperationTag = "SAMPLE_FRACTION"
+	SHUFFLE              OperationTag = "SHUFFLE"
+	SKIP                 OperationTag = "SKIP"
+	SORTED               OperationTag = "SORTED"
+	TOP_K                OperationTag = "TOP_K"
 )
 
+// Tags returns every built-in or registered operation tag in alphabetical order
+func Tags() []OperationTag {
+	registry.RLock()
+	tags := make([]OperationTag, 0, len(traits)+len(registry.operations))
+	for tag := range registry.operations {
+		tags = append(tags, tag)
+	}
+	registry.RUnlock()
+	for tag := range traits {
+		tags = append(tags, tag)
+	}
+	sort.Slice(tags, func(i, j int) bool {
+		return tags[i] < tags[j]
+	})
+	return tags
+}
+
 type OperationDescriptor struct {
 	tag    OperationTag
 	params []interface{}
 }
+
+// withDescriptor returns a copy of a pipeline with one more step
+// Streams built from the same one share its descriptors, so appending in place would let them overwrite each other's steps
+func withDescriptor(descriptors []OperationDescriptor, desc OperationDescriptor) []OperationDescriptor {
+	result := make([]OperationDescriptor, len(descriptors), len(descriptors)+1)
+	copy(result, descriptors)
+	return append(result, desc)
+}
@@ -0,0 +1,94 @@
+package stream_test
+
+import (
+	"errors"
+	"reflect"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Test typed collection", func() {
+	ginkgo.When("Executing CollectInto", func() {
+		ginkgo.It("should append items to a typed slice", func() {
+			result := []int{0}
+			err := stream.CollectInto(stream.OfParallel(2, 1, 2, 3).MapOrdered(func(item interface{}) interface{} {
+				return item.(int) * 2
+			}), &result)
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(result).To(gomega.Equal([]int{0, 2, 4, 6}))
+		})
+		ginkgo.It("should reuse the capacity of the slice", func() {
+			result := make([]string, 0, 8)
+			err := stream.CollectInto(stream.Of("a", "b"), &result)
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(result).To(gomega.Equal([]string{"a", "b"}))
+			gomega.Expect(cap(result)).To(gomega.Equal(8))
+		})
+		ginkgo.It("should name the mistyped element and leave the slice untouched", func() {
+			result := []int{7}
+			err := stream.CollectInto(stream.Of(1, 2, "3"), &result)
+			var collectErr *stream.CollectError
+			gomega.Expect(errors.As(err, &collectErr)).To(gomega.BeTrue())
+			gomega.Expect(collectErr.Index).To(gomega.Equal(2))
+			gomega.Expect(collectErr.Target).To(gomega.Equal(reflect.TypeOf(0)))
+			gomega.Expect(err.Error()).To(gomega.Equal("element 2: value of type string cannot be collected as int"))
+			gomega.Expect(result).To(gomega.Equal([]int{7}))
+		})
+		ginkgo.It("should accept nil only for nillable types", func() {
+			var pointers []*node
+			gomega.Expect(stream.CollectInto(stream.Of(&node{1}, nil), &pointers)).To(gomega.Succeed())
+			gomega.Expect(pointers).To(gomega.Equal([]*node{{1}, nil}))
+			var values []node
+			err := stream.CollectInto(stream.Of(node{1}, nil), &values)
+			gomega.Expect(err).To(gomega.MatchError("element 1: nil cannot be collected as stream_test.node"))
+		})
+		ginkgo.It("should collect into interfaces", func() {
+			var result []error
+			failure := errors.New("failure")
+			gomega.Expect(stream.CollectInto(stream.Of(failure, nil), &result)).To(gomega.Succeed())
+			gomega.Expect(result).To(gomega.Equal([]error{failure, nil}))
+		})
+	})
+	ginkgo.When("Executing CollectIntoMap", func() {
+		ginkgo.It("should put items into a typed map", func() {
+			var result map[string]int
+			err := stream.CollectIntoMap(stream.Of("a", "bb", "ccc"), &result, func(item interface{}) interface{} {
+				return item
+			}, func(item interface{}) interface{} {
+				return len(item.(string))
+			})
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(result).To(gomega.Equal(map[string]int{"a": 1, "bb": 2, "ccc": 3}))
+		})
+		ginkgo.It("should add to an existing map", func() {
+			result := map[int]bool{0: true}
+			err := stream.CollectIntoMap(stream.Of(1, 2), &result, func(item interface{}) interface{} {
+				return item
+			}, func(item interface{}) interface{} {
+				return item.(int)%2 == 0
+			})
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(result).To(gomega.Equal(map[int]bool{0: true, 1: false, 2: true}))
+		})
+		ginkgo.It("should name the item with a mistyped key or value and leave the map untouched", func() {
+			result := map[string]int{}
+			identity := func(item interface{}) interface{} {
+				return item
+			}
+			err := stream.CollectIntoMap(stream.Of("a", 2), &result, identity, func(item interface{}) interface{} {
+				return 1
+			})
+			gomega.Expect(err).To(gomega.MatchError("key 1: value of type int cannot be collected as string"))
+			err = stream.CollectIntoMap(stream.Of("a", "b"), &result, identity, func(item interface{}) interface{} {
+				if item == "b" {
+					return nil
+				}
+				return 1
+			})
+			gomega.Expect(err).To(gomega.MatchError("value 1: nil cannot be collected as int"))
+			gomega.Expect(result).To(gomega.BeEmpty())
+		})
+	})
+})
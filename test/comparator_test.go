@@ -0,0 +1,82 @@
+package stream_test
+
+import (
+	"time"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/dynastywind/go-stream/util"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+type employee struct {
+	name   string
+	dept   string
+	salary int
+}
+
+func employeeDept(item interface{}) interface{} {
+	return item.(*employee).dept
+}
+
+func employeeSalary(item interface{}) interface{} {
+	return item.(*employee).salary
+}
+
+var _ = ginkgo.Describe("Test comparators", func() {
+	alice := &employee{"alice", "dev", 300}
+	bob := &employee{"bob", "ops", 200}
+	carol := &employee{"carol", "dev", 100}
+	dave := &employee{"dave", "ops", 200}
+
+	ginkgo.Context("Natural order test", func() {
+		ginkgo.When("Comparing built-in ordered types", func() {
+			ginkgo.It("should compare them by their natural order", func() {
+				gomega.Expect(util.NaturalOrder(1, 2)).To(gomega.BeTrue())
+				gomega.Expect(util.NaturalOrder(uint8(2), uint8(1))).To(gomega.BeFalse())
+				gomega.Expect(util.NaturalOrder(1.5, 2.5)).To(gomega.BeTrue())
+				gomega.Expect(util.NaturalOrder("a", "b")).To(gomega.BeTrue())
+				gomega.Expect(util.NaturalOrder(time.Second, time.Minute)).To(gomega.BeTrue())
+				gomega.Expect(util.ReverseOrder(1, 2)).To(gomega.BeFalse())
+			})
+			ginkgo.It("should panic on types without natural order", func() {
+				gomega.Expect(func() {
+					util.NaturalOrder(struct{}{}, struct{}{})
+				}).To(gomega.Panic())
+				gomega.Expect(func() {
+					util.NaturalOrder(int8(1), int16(1))
+				}).To(gomega.Panic())
+			})
+		})
+	})
+	ginkgo.Context("Combinator test", func() {
+		ginkgo.When("Sorting by several keys", func() {
+			ginkgo.It("should order by department then by descending salary then by name", func() {
+				arr := stream.Of(alice, bob, carol, dave).Sorted(
+					util.Comparing(employeeDept).
+						ThenComparing(util.Comparing(employeeSalary).Reversed()).
+						ThenComparingKey(func(item interface{}) interface{} {
+							return item.(*employee).name
+						})).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{alice, carol, bob, dave}))
+			})
+		})
+		ginkgo.When("Finding extremes with a comparator", func() {
+			ginkgo.It("should find the best paid employee", func() {
+				max := stream.OfParallel(2, alice, bob, carol, dave).Max(util.Comparing(employeeSalary))
+				gomega.Expect(max.Get()).To(gomega.Equal(alice))
+			})
+		})
+		ginkgo.When("Comparing with nils", func() {
+			ginkgo.It("should put nils first", func() {
+				var none *employee
+				arr := stream.Of(bob, none, carol, nil).Sorted(util.ComparingWith(employeeSalary, util.NaturalOrder).NullsFirst()).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{none, nil, carol, bob}))
+			})
+			ginkgo.It("should put nils last", func() {
+				arr := stream.Of(nil, 3, nil, 1).Sorted(util.NaturalOrder.NullsLast()).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{1, 3, nil, nil}))
+			})
+		})
+	})
+})
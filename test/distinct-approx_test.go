@@ -0,0 +1,77 @@
+package stream_test
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/dynastywind/go-stream/util"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+type event struct {
+	id   string
+	time time.Time
+}
+
+func eventID(item interface{}) string {
+	return item.(event).id
+}
+
+func eventTime(item interface{}) time.Time {
+	return item.(event).time
+}
+
+func itoa(item interface{}) string {
+	return strconv.Itoa(item.(int))
+}
+
+var _ = ginkgo.Describe("Test approximate distinct operations", func() {
+	ginkgo.Context("Bloom filter test", func() {
+		ginkgo.When("Testing added and unknown strings", func() {
+			ginkgo.It("should never miss an added string and rarely report an unknown one", func() {
+				filter := util.NewBloomFilter(10000, 0.01)
+				for i := 0; i < 10000; i++ {
+					filter.Add(strconv.Itoa(i))
+				}
+				falsePositives := 0
+				for i := 0; i < 10000; i++ {
+					gomega.Expect(filter.Test(strconv.Itoa(i))).To(gomega.BeTrue())
+					if filter.Test(strconv.Itoa(i + 10000)) {
+						falsePositives++
+					}
+				}
+				gomega.Expect(falsePositives).To(gomega.BeNumerically("<", 200))
+			})
+		})
+	})
+	ginkgo.When("Executing DistinctApprox", func() {
+		ginkgo.It("should drop every duplicate", func() {
+			arr := stream.OfParallel(2, 1, 2, 1, 3, 2, 4).DistinctApprox(itoa, 100, 0.001).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{1, 2, 3, 4}))
+		})
+	})
+	ginkgo.When("Executing DistinctWithin", func() {
+		ginkgo.It("should only drop duplicates among the last keys", func() {
+			arr := stream.Of(1, 2, 1, 3, 4, 1, 4).DistinctWithin(itoa, 2).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{1, 2, 3, 4, 1}))
+		})
+		ginkgo.It("should behave the same in a parallel stream", func() {
+			arr := stream.Of(1, 2, 1, 3, 4, 1, 4).DistinctWithin(itoa, 2).AsParallel(3).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{1, 2, 3, 4, 1}))
+		})
+	})
+	ginkgo.When("Executing DistinctWithinTime", func() {
+		ginkgo.It("should only drop duplicates close in time", func() {
+			start := time.Date(2021, 1, 1, 0, 0, 0, 0, time.UTC)
+			a0 := event{"a", start}
+			b1 := event{"b", start.Add(time.Minute)}
+			a2 := event{"a", start.Add(2 * time.Minute)}
+			a9 := event{"a", start.Add(9 * time.Minute)}
+			b9 := event{"b", start.Add(9 * time.Minute)}
+			arr := stream.OfParallel(2, a0, b1, a2, a9, b9).DistinctWithinTime(eventID, eventTime, 5*time.Minute).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{a0, b1, a9, b9}))
+		})
+	})
+})
@@ -0,0 +1,180 @@
+package stream_test
+
+import (
+	"errors"
+	"sync"
+
+	"github.com/dynastywind/go-stream/expr"
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	"github.com/onsi/gomega"
+)
+
+type request struct {
+	Status    string `json:"status"`
+	LatencyMs int    `json:"latency_ms"`
+	User      *user
+}
+
+type user struct {
+	ID   int
+	Tags []string
+}
+
+func records() []interface{} {
+	return []interface{}{
+		map[string]interface{}{"status": "ok", "latency_ms": 250, "user": map[string]interface{}{"id": 1}},
+		map[string]interface{}{"status": "ok", "latency_ms": 120, "user": map[string]interface{}{"id": 2}},
+		map[string]interface{}{"status": "error", "latency_ms": 900, "user": map[string]interface{}{"id": 3}},
+		map[string]interface{}{"status": "ok", "latency_ms": 400.5},
+	}
+}
+
+var _ = ginkgo.Describe("Test expressions", func() {
+	ginkgo.When("Filtering and mapping records", func() {
+		ginkgo.It("should read maps", func() {
+			arr := stream.Of(records()...).
+				Filter(expr.MustCompile(`status == "ok" && latency_ms > 200`).Predicate()).
+				Map(expr.MustCompile(`{user: user.id, ms: latency_ms}`).Mapper()).
+				ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{
+				map[string]interface{}{"user": 1, "ms": 250},
+				map[string]interface{}{"user": nil, "ms": 400.5},
+			}))
+		})
+		ginkgo.It("should read structs by JSON name, name or name ignoring case", func() {
+			arr := stream.Of(
+				request{Status: "ok", LatencyMs: 30, User: &user{ID: 7, Tags: []string{"admin"}}},
+				&request{Status: "ok", LatencyMs: 10, User: &user{ID: 8}},
+				request{Status: "error", LatencyMs: 20},
+			).FilterOrdered(expr.MustCompile(`status in ["ok", "warn"] && User.id > 7 || user.tags[0] == 'admin'`).Predicate()).
+				MapOrdered(expr.MustCompile(`latency_ms * 2 + LatencyMs % 7`).Mapper()).
+				ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{62, 23}))
+		})
+		ginkgo.It("should refer to the record itself as _", func() {
+			arr := stream.Of(1, 2, 3, 4).Filter(expr.MustCompile(`_ % 2 == 0`).Predicate()).Map(expr.MustCompile(`-_ / 4`).Mapper()).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{-0.5, -1.0}))
+		})
+		ginkgo.It("should compare numbers by value and concatenate strings", func() {
+			e := expr.MustCompile(`[latency_ms == 120.0, status + "!", "rr" in status, "missing" in _, !(latency_ms <= 120)]`)
+			value, err := e.Eval(records()[2])
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(value).To(gomega.Equal([]interface{}{false, "error!", true, false, true}))
+		})
+	})
+
+	ginkgo.When("Reading records missing a field", func() {
+		ginkgo.It("should filter them out rather than panic", func() {
+			items := append(records(), map[string]interface{}{"status": "ok"}, map[string]interface{}{})
+			for _, src := range []string{`latency_ms > 200`, `latency_ms * 2 > 400`, `-latency_ms < -200`, `retried`, `retried && latency_ms > 0`} {
+				arr := stream.OfParallel(3, items...).FilterOrdered(expr.MustCompile(src).Predicate()).ToArray()
+				gomega.Expect(arr).NotTo(gomega.ContainElement(items[4]), src)
+				gomega.Expect(arr).NotTo(gomega.ContainElement(items[5]), src)
+			}
+			arr := stream.OfParallel(3, items...).FilterOrdered(expr.MustCompile(`latency_ms > 200`).Predicate()).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{records()[0], records()[2], records()[3]}))
+		})
+		ginkgo.It("should keep null in projections", func() {
+			value, err := expr.MustCompile(`{ms: latency_ms * 2, found: "x" in tags}`).Eval(map[string]interface{}{})
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(value).To(gomega.Equal(map[string]interface{}{"ms": nil, "found": false}))
+		})
+	})
+
+	ginkgo.When("Sorting records", func() {
+		ginkgo.It("should compare keys in turn, nulls first", func() {
+			less, err := expr.CompileLess(`status desc, user.id, latency_ms asc`)
+			gomega.Expect(err).To(gomega.BeNil())
+			arr := stream.Of(records()...).Sorted(less).Map(expr.MustCompile(`latency_ms`).Mapper()).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{400.5, 250, 120, 900}))
+		})
+		ginkgo.It("should panic when keys cannot be compared", func() {
+			less, _ := expr.CompileLess(`latency_ms, status`)
+			gomega.Expect(func() {
+				less(map[string]interface{}{"latency_ms": "slow"}, map[string]interface{}{"latency_ms": 2})
+			}).To(gomega.PanicWith(&expr.EvalError{Column: 1, Message: "cannot compare string with number"}))
+		})
+	})
+
+	table.DescribeTable("Reporting compile errors by column",
+		func(src string, column int, message string) {
+			_, err := expr.Compile(src)
+			var syntaxErr *expr.SyntaxError
+			gomega.Expect(errors.As(err, &syntaxErr)).To(gomega.BeTrue())
+			gomega.Expect(syntaxErr).To(gomega.Equal(&expr.SyntaxError{Column: column, Message: message}))
+		},
+		table.Entry("with a missing operand", `status == "ok" && `, 19, `unexpected end of expression`),
+		table.Entry("with an unknown character", `latency_ms # 2`, 12, `unexpected character '#'`),
+		table.Entry("with an unterminated string", `status == "ok`, 11, `unterminated string`),
+		table.Entry("with a chained comparison", `1 < latency_ms < 5`, 16, `comparisons cannot be chained, use && between "<" and "<"`),
+		table.Entry("with an unclosed bracket", `[1, 2`, 6, `expected "]", found end of expression`),
+		table.Entry("with a missing key", `{user: 1, : 2}`, 11, `expected a key, found ":"`),
+		table.Entry("with characters counted rather than bytes", `"é" == ) `, 8, `unexpected ")"`),
+	)
+
+	ginkgo.It("should report the column of sort keys within the whole source", func() {
+		_, err := expr.CompileLess(`status, latency_ms +`)
+		gomega.Expect(err).To(gomega.MatchError(`column 21: unexpected end of expression`))
+	})
+
+	table.DescribeTable("Reporting evaluation errors by column",
+		func(src string, record interface{}, message string) {
+			_, err := expr.MustCompile(src).Eval(record)
+			gomega.Expect(err).To(gomega.MatchError(message))
+		},
+		table.Entry("when comparing a string with a number", `latency_ms > "200"`, records()[0], `column 12: cannot compare number with string`),
+		table.Entry("when combining non booleans", `status && true`, records()[0], `column 8: expected a boolean, got string`),
+		table.Entry("when dividing by zero", `latency_ms / (latency_ms - 250)`, records()[0], `column 12: division by zero`),
+		table.Entry("when looking for a list among keys", `[1] in _`, map[interface{}]interface{}{1: true}, `column 5: cannot look for list in an object, as it is not a valid key`),
+		table.Entry("when indexing an object with a list", `_[[1]]`, map[interface{}]interface{}{1: true}, `column 2: cannot index map[interface {}]interface {} with list`),
+		table.Entry("when reading a missing struct field", `user.name`, request{User: &user{}}, `column 5: stream_test.user has no field "name"`),
+	)
+
+	ginkgo.It("should not negate an operand which cannot be evaluated", func() {
+		value, err := expr.MustCompile(`!missing`).Eval(request{})
+		gomega.Expect(err).To(gomega.MatchError(`column 2: stream_test.request has no field "missing"`))
+		gomega.Expect(value).To(gomega.Equal(false))
+	})
+
+	ginkgo.When("Reporting evaluation errors instead of panicking", func() {
+		items := []interface{}{request{Status: "ok", LatencyMs: 300}, "not a record", request{Status: "ok", LatencyMs: 100}, map[string]interface{}{"latency_ms": "slow"}}
+		ginkgo.It("should leave out records failing a predicate", func() {
+			var mu sync.Mutex
+			var errs []error
+			arr := stream.OfParallel(4, items...).FilterOrdered(expr.MustCompile(`latency_ms > 200`).PredicateWith(func(err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, err)
+			})).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{items[0]}))
+			gomega.Expect(errs).To(gomega.HaveLen(2))
+		})
+		ginkgo.It("should map records failing a mapper to nil", func() {
+			var mu sync.Mutex
+			var errs []error
+			arr := stream.OfParallel(4, items...).MapOrdered(expr.MustCompile(`latency_ms + 1`).MapperWith(func(err error) {
+				mu.Lock()
+				defer mu.Unlock()
+				errs = append(errs, err)
+			})).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{301, nil, 101, nil}))
+			gomega.Expect(errs).To(gomega.HaveLen(2))
+		})
+	})
+
+	ginkgo.It("should compile expressions of pipeline definitions", func() {
+		s, err := buildPipeline(`{"steps": [
+			{"op": "FILTER", "params": [{"expr": "status == 'ok'"}]},
+			{"op": "SORTED", "params": [{"expr": "latency_ms desc"}]},
+			{"op": "MAP", "params": [{"expr": "user.id"}]}
+		]}`, stream.Of(records()...))
+		gomega.Expect(err).To(gomega.BeNil())
+		gomega.Expect(s.ToArray()).To(gomega.Equal([]interface{}{nil, 1, 2}))
+		_, err = buildPipeline(`{"steps": [{"op": "FILTER", "params": [{"expr": "status = 'ok'"}]}]}`, stream.Of())
+		gomega.Expect(err).To(gomega.MatchError(`steps[0].params[0]: column 8: unexpected character '='`))
+		_, err = buildPipeline(`{"steps": [{"op": "DISTINCT", "params": [{"expr": "status"}]}]}`, stream.Of())
+		gomega.Expect(err).To(gomega.MatchError(`steps[0].params[0]: expected a func(interface {}) string, which cannot be written as an expression`))
+	})
+})
@@ -0,0 +1,222 @@
+package stream_test
+
+import (
+	"encoding/gob"
+	"errors"
+	"io"
+	"math/rand"
+	"os"
+	"sort"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/dynastywind/go-stream/util"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+type ExternalRecord struct {
+	Key   int
+	Index int
+}
+
+type unregisteredRecord struct {
+	Key int
+}
+
+// countingCodec wraps GobCodec to count how many items are spilled and read back
+type countingCodec struct {
+	encoded *int
+	decoded *int
+}
+
+func (c countingCodec) NewEncoder(w io.Writer) util.Encoder {
+	return countingEncoder{util.GobCodec.NewEncoder(w), c.encoded}
+}
+
+func (c countingCodec) NewDecoder(r io.Reader) util.Decoder {
+	return countingDecoder{util.GobCodec.NewDecoder(r), c.decoded}
+}
+
+type countingEncoder struct {
+	util.Encoder
+	encoded *int
+}
+
+func (e countingEncoder) Encode(item interface{}) error {
+	*e.encoded++
+	return e.Encoder.Encode(item)
+}
+
+type countingDecoder struct {
+	util.Decoder
+	decoded *int
+}
+
+func (d countingDecoder) Decode() (interface{}, error) {
+	if d.decoded != nil {
+		*d.decoded++
+	}
+	return d.Decoder.Decode()
+}
+
+// failingCodec fails to read back any run file
+type failingCodec struct{}
+
+func (failingCodec) NewEncoder(w io.Writer) util.Encoder {
+	return util.GobCodec.NewEncoder(w)
+}
+
+func (failingCodec) NewDecoder(r io.Reader) util.Decoder {
+	return failingDecoder{}
+}
+
+type failingDecoder struct{}
+
+func (failingDecoder) Decode() (interface{}, error) {
+	return nil, errors.New("corrupted run")
+}
+
+func iterate(arr []interface{}) func() (interface{}, bool) {
+	i := 0
+	return func() (interface{}, bool) {
+		if i == len(arr) {
+			return nil, false
+		}
+		i++
+		return arr[i-1], true
+	}
+}
+
+func init() {
+	gob.Register(ExternalRecord{})
+}
+
+func lessInt(a, b interface{}) bool {
+	return a.(int) < b.(int)
+}
+
+var _ = ginkgo.Describe("Test external sort", func() {
+	var dir string
+	ginkgo.BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "stream-external-sort")
+		gomega.Expect(err).To(gomega.BeNil())
+	})
+	ginkgo.AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+	runFiles := func() int {
+		entries, err := os.ReadDir(dir)
+		gomega.Expect(err).To(gomega.BeNil())
+		return len(entries)
+	}
+
+	ginkgo.When("Sorting more items than a run holds", func() {
+		ginkgo.It("should spill runs while reading items and merge them back lazily", func() {
+			r := rand.New(rand.NewSource(1))
+			arr := make([]interface{}, 1000)
+			expected := make([]int, len(arr))
+			for i := range arr {
+				expected[i] = r.Intn(500)
+				arr[i] = expected[i]
+			}
+			sort.Ints(expected)
+			read := iterate(arr)
+			spilledWhileReading := 0
+			encoded, decoded := 0, 0
+			it, err := util.ExternalSort(func() (interface{}, bool) {
+				spilledWhileReading = runFiles()
+				return read()
+			}, lessInt, util.ExternalSortOptions{
+				RunSize: 64,
+				Dir:     dir,
+				Codec:   countingCodec{&encoded, &decoded},
+			})
+			gomega.Expect(err).To(gomega.BeNil())
+			// The last 1000 % 64 items stay in memory
+			gomega.Expect(spilledWhileReading).To(gomega.Equal(15))
+			gomega.Expect(encoded).To(gomega.Equal(960))
+			// Only the head of every run is read before iterating
+			gomega.Expect(decoded).To(gomega.Equal(15))
+			var sorted []int
+			for it.Next() {
+				sorted = append(sorted, it.Item().(int))
+			}
+			gomega.Expect(it.Err()).To(gomega.BeNil())
+			gomega.Expect(sorted).To(gomega.Equal(expected))
+			gomega.Expect(it.Close()).To(gomega.BeNil())
+			gomega.Expect(runFiles()).To(gomega.Equal(0))
+		})
+		ginkgo.It("should keep the order of equal items in a stream", func() {
+			items := make([]interface{}, 500)
+			for i := range items {
+				items[i] = ExternalRecord{Key: (i * 7) % 10, Index: i}
+			}
+			arr := stream.OfParallel(2, items...).SortedExternal(func(a, b interface{}) bool {
+				return a.(ExternalRecord).Key < b.(ExternalRecord).Key
+			}, util.ExternalSortOptions{
+				RunSize: 30,
+				Dir:     dir,
+			}).ToArray()
+			gomega.Expect(arr).To(gomega.HaveLen(500))
+			for i := 1; i < len(arr); i++ {
+				prev, cur := arr[i-1].(ExternalRecord), arr[i].(ExternalRecord)
+				gomega.Expect(prev.Key < cur.Key || prev.Key == cur.Key && prev.Index < cur.Index).To(gomega.BeTrue())
+			}
+			gomega.Expect(runFiles()).To(gomega.Equal(0))
+		})
+	})
+	ginkgo.When("Sorting fewer items than a run holds", func() {
+		ginkgo.It("should sort in memory", func() {
+			encoded := 0
+			it, err := util.ExternalSort(iterate([]interface{}{3, 1, 2}), lessInt, util.ExternalSortOptions{
+				RunSize: 3,
+				Dir:     dir,
+				Codec:   countingCodec{encoded: &encoded},
+			})
+			gomega.Expect(err).To(gomega.BeNil())
+			defer it.Close()
+			var sorted []interface{}
+			for it.Next() {
+				sorted = append(sorted, it.Item())
+			}
+			gomega.Expect(sorted).To(gomega.Equal([]interface{}{1, 2, 3}))
+			gomega.Expect(encoded).To(gomega.Equal(0))
+		})
+	})
+	ginkgo.When("Failing to write or read run files", func() {
+		lessRecord := func(a, b interface{}) bool {
+			return a.(unregisteredRecord).Key < b.(unregisteredRecord).Key
+		}
+		ginkgo.It("should return the error met writing a run", func() {
+			_, err := util.ExternalSort(iterate([]interface{}{unregisteredRecord{2}, unregisteredRecord{1}}), lessRecord, util.ExternalSortOptions{
+				RunSize: 1,
+				Dir:     dir,
+			})
+			gomega.Expect(err).NotTo(gomega.BeNil())
+			gomega.Expect(runFiles()).To(gomega.Equal(0))
+		})
+		ginkgo.It("should report the error and sort in memory within a stream", func() {
+			var errs []error
+			arr := stream.Of(unregisteredRecord{2}, unregisteredRecord{3}, unregisteredRecord{1}).SortedExternal(lessRecord, util.ExternalSortOptions{
+				RunSize: 1,
+				Dir:     dir,
+				OnError: func(err error) {
+					errs = append(errs, err)
+				},
+			}).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{unregisteredRecord{1}, unregisteredRecord{2}, unregisteredRecord{3}}))
+			gomega.Expect(errs).To(gomega.HaveLen(1))
+			gomega.Expect(runFiles()).To(gomega.Equal(0))
+		})
+		ginkgo.It("should return the error met reading a run", func() {
+			_, err := util.ExternalSort(iterate([]interface{}{3, 1, 2}), lessInt, util.ExternalSortOptions{
+				RunSize: 1,
+				Dir:     dir,
+				Codec:   failingCodec{},
+			})
+			gomega.Expect(err).To(gomega.MatchError("corrupted run"))
+			gomega.Expect(runFiles()).To(gomega.Equal(0))
+		})
+	})
+})
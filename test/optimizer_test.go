@@ -0,0 +1,134 @@
+package stream_test
+
+import (
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/dynastywind/go-stream/util"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	"github.com/onsi/gomega"
+)
+
+func stageTags(s stream.Stream) []stream.OperationTag {
+	var tags []stream.OperationTag
+	for _, stage := range s.Explain().Stages {
+		tags = append(tags, stage.Tag)
+	}
+	return tags
+}
+
+func byTens(a, b interface{}) bool {
+	return a.(int)/10 < b.(int)/10
+}
+
+func plusOne(item interface{}) interface{} {
+	return item.(int) + 1
+}
+
+func isOdd(item interface{}) bool {
+	return item.(int)%2 == 1
+}
+
+var _ = ginkgo.Describe("Test pipeline optimizer", func() {
+	items := shuffledInts(500)
+	ginkgo.It("should call a mapper before a limit only on the items kept", func() {
+		calls := 0
+		counter := func(item interface{}) interface{} {
+			calls++
+			return item
+		}
+		gomega.Expect(stream.Of(1, 2, 3, 4).Map(counter).Limit(1).ToArray()).To(gomega.Equal([]interface{}{1}))
+		gomega.Expect(calls).To(gomega.Equal(1))
+		calls = 0
+		gomega.Expect(stream.Of(1, 2, 3, 4).Map(counter).Limit(1).Unoptimized().ToArray()).To(gomega.Equal([]interface{}{1}))
+		gomega.Expect(calls).To(gomega.Equal(4))
+	})
+	table.DescribeTable("Comparing optimized and unoptimized pipelines",
+		func(build func(stream.Stream) stream.Stream, expected []stream.OperationTag) {
+			for _, source := range []stream.Stream{stream.Of(items...), stream.OfParallel(4, items...)} {
+				optimized := build(source)
+				unoptimized := build(source).Unoptimized()
+				gomega.Expect(optimized.ToArray()).To(gomega.Equal(unoptimized.ToArray()))
+				if !source.IsParallel() {
+					gomega.Expect(stageTags(optimized)).To(gomega.Equal(expected))
+				}
+			}
+		},
+		table.Entry("fusing maps", func(s stream.Stream) stream.Stream {
+			return s.MapOrdered(double).MapOrdered(plusOne).MapOrdered(double)
+		}, []stream.OperationTag{stream.MAP_ORDERED}),
+		table.Entry("fusing filters", func(s stream.Stream) stream.Stream {
+			return s.FilterOrdered(isOdd).FilterOrdered(func(item interface{}) bool {
+				return item.(int) > 100
+			})
+		}, []stream.OperationTag{stream.FILTER_ORDERED}),
+		table.Entry("pushing a limit before maps", func(s stream.Stream) stream.Stream {
+			return s.FilterOrdered(isOdd).MapOrdered(double).MapOrdered(plusOne).Limit(20)
+		}, []stream.OperationTag{stream.FILTER_ORDERED, stream.LIMIT, stream.MAP_ORDERED}),
+		table.Entry("fusing limits", func(s stream.Stream) stream.Stream {
+			return s.Limit(30).Limit(10).Limit(20)
+		}, []stream.OperationTag{stream.LIMIT}),
+		table.Entry("turning a sort and a limit into a bounded heap", func(s stream.Stream) stream.Stream {
+			return s.Sorted(byTens).Limit(25)
+		}, []stream.OperationTag{stream.BOTTOM_K}),
+		table.Entry("turning a sort, a map and a limit into a bounded heap and a map", func(s stream.Stream) stream.Stream {
+			return s.Sorted(lessInt).MapOrdered(double).Limit(10)
+		}, []stream.OperationTag{stream.BOTTOM_K, stream.MAP_ORDERED}),
+		table.Entry("keeping a sort before a stable sort", func(s stream.Stream) stream.Stream {
+			return s.Sorted(lessInt).Sorted(byTens)
+		}, []stream.OperationTag{stream.SORTED, stream.SORTED}),
+		table.Entry("keeping stages around a shuffle", func(s stream.Stream) stream.Stream {
+			return s.WithRandom(seeded()).MapOrdered(double).Shuffle().Limit(5)
+		}, []stream.OperationTag{stream.MAP_ORDERED, stream.SHUFFLE, stream.LIMIT}),
+	)
+	ginkgo.When("Sorting before an unstable sort", func() {
+		ginkgo.It("should drop the first sort", func() {
+			s := stream.Of(items...).Sorted(byTens).SortedWith(lessInt, util.PdqSorter)
+			gomega.Expect(stageTags(s)).To(gomega.Equal([]stream.OperationTag{stream.SORTED}))
+			gomega.Expect(s.ToArray()).To(gomega.Equal(stream.Of(items...).Sorted(lessInt).ToArray()))
+		})
+	})
+	ginkgo.When("Mapping in a parallel stream without keeping order", func() {
+		ginkgo.It("should not push a limit before the map", func() {
+			s := stream.OfParallel(2, items...).Map(double).Limit(5)
+			gomega.Expect(stageTags(s)).To(gomega.Equal([]stream.OperationTag{stream.MAP, stream.LIMIT}))
+		})
+	})
+	ginkgo.When("Branching a stream into two", func() {
+		ginkgo.It("should keep the steps of each branch apart", func() {
+			identity := func(item interface{}) interface{} {
+				return item
+			}
+			times10 := func(item interface{}) interface{} {
+				return item.(int) * 10
+			}
+			for _, source := range []stream.Stream{stream.Of(1, 2, 3, 4, 5), stream.OfParallel(2, 1, 2, 3, 4, 5)} {
+				a := source.MapOrdered(identity).MapOrdered(identity).MapOrdered(identity)
+				x := a.MapOrdered(times10)
+				y := a.Limit(1)
+				expected := []interface{}{10, 20, 30, 40, 50}
+				gomega.Expect(x.ToArray()).To(gomega.Equal(expected))
+				gomega.Expect(x.Unoptimized().ToArray()).To(gomega.Equal(expected))
+				gomega.Expect(stageTags(x.Unoptimized())).To(gomega.Equal([]stream.OperationTag{stream.MAP_ORDERED, stream.MAP_ORDERED, stream.MAP_ORDERED, stream.MAP_ORDERED}))
+				gomega.Expect(y.ToArray()).To(gomega.Equal([]interface{}{1}))
+			}
+		})
+	})
+	ginkgo.When("Disabling the optimizer", func() {
+		ginkgo.It("should run every stage as recorded", func() {
+			calls := 0
+			count := func(item interface{}) interface{} {
+				calls++
+				return item
+			}
+			stream.Of(items...).MapOrdered(count).Limit(3).ToArray()
+			gomega.Expect(calls).To(gomega.Equal(3))
+			calls = 0
+			stream.Of(items...).MapOrdered(count).Limit(3).Unoptimized().ToArray()
+			gomega.Expect(calls).To(gomega.Equal(500))
+		})
+		ginkgo.It("should stay disabled after conversion", func() {
+			s := stream.Of(1, 2).Map(double).Map(double).Unoptimized().AsParallel(2).AsSequence()
+			gomega.Expect(stageTags(s)).To(gomega.Equal([]stream.OperationTag{stream.MAP, stream.MAP}))
+		})
+	})
+})
@@ -0,0 +1,113 @@
+package stream_test
+
+import (
+	"encoding/json"
+	"net"
+	"time"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/dynastywind/go-stream/util"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+type response struct {
+	Oldest *util.Optional `json:"oldest"`
+	Newest *util.Optional `json:"newest"`
+}
+
+var _ = ginkgo.Describe("Test optional encoding", func() {
+	ginkgo.When("Encoding to JSON", func() {
+		ginkgo.It("should encode an empty optional as null", func() {
+			b, err := json.Marshal(response{
+				Oldest: stream.Of("a", "b").FindFirst(),
+				Newest: stream.Of().FindFirst(),
+			})
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(string(b)).To(gomega.Equal(`{"oldest":"a","newest":null}`))
+		})
+		ginkgo.It("should round trip through JSON", func() {
+			var decoded response
+			err := json.Unmarshal([]byte(`{"oldest":{"id":1},"newest":null}`), &decoded)
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(decoded.Oldest.Get()).To(gomega.Equal(map[string]interface{}{"id": 1.0}))
+			gomega.Expect(decoded.Newest.IsPresent()).To(gomega.BeFalse())
+			gomega.Expect(decoded.Newest.OrElse("none")).To(gomega.Equal("none"))
+			gomega.Expect(decoded.Newest.Map(func(data interface{}) interface{} {
+				return data
+			}).IsPresent()).To(gomega.BeFalse())
+			gomega.Expect(decoded.Newest.String()).To(gomega.Equal("Optional.empty"))
+			b, err := json.Marshal(decoded)
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(string(b)).To(gomega.Equal(`{"oldest":{"id":1},"newest":null}`))
+		})
+		ginkgo.It("should empty an optional on null", func() {
+			opt := util.Of(1)
+			gomega.Expect(json.Unmarshal([]byte("null"), opt)).To(gomega.Succeed())
+			gomega.Expect(opt.IsPresent()).To(gomega.BeFalse())
+		})
+		ginkgo.It("should decode into the pointer held", func() {
+			var t time.Time
+			opt := util.Of(&t)
+			gomega.Expect(json.Unmarshal([]byte(`"2021-06-01T00:00:00Z"`), opt)).To(gomega.Succeed())
+			gomega.Expect(t).To(gomega.Equal(time.Date(2021, 6, 1, 0, 0, 0, 0, time.UTC)))
+		})
+	})
+	ginkgo.When("Formatting", func() {
+		ginkgo.It("should tell an empty optional apart from an empty string", func() {
+			gomega.Expect(util.OfEmpty().String()).To(gomega.Equal("Optional.empty"))
+			gomega.Expect(util.Of("").String()).To(gomega.Equal(""))
+			gomega.Expect(util.Of(42).String()).To(gomega.Equal("42"))
+		})
+	})
+	ginkgo.When("Encoding to text", func() {
+		ginkgo.It("should use the text encoding of the value", func() {
+			b, err := util.Of(net.IPv4(10, 0, 0, 1)).MarshalText()
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(string(b)).To(gomega.Equal("10.0.0.1"))
+			b, err = util.Of(42).MarshalText()
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(string(b)).To(gomega.Equal("42"))
+		})
+		ginkgo.It("should round trip through text", func() {
+			b, err := util.OfEmpty().MarshalText()
+			gomega.Expect(err).To(gomega.BeNil())
+			decoded := util.Of("stale")
+			gomega.Expect(decoded.UnmarshalText(b)).To(gomega.Succeed())
+			gomega.Expect(decoded.IsPresent()).To(gomega.BeFalse())
+			gomega.Expect(decoded.UnmarshalText([]byte("hello"))).To(gomega.Succeed())
+			gomega.Expect(decoded.Get()).To(gomega.Equal("hello"))
+		})
+	})
+	ginkgo.When("Writing to and reading from SQL", func() {
+		ginkgo.It("should write an empty optional as NULL", func() {
+			value, err := util.OfEmpty().Value()
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(value).To(gomega.BeNil())
+			value, err = util.Of(int32(7)).Value()
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(value).To(gomega.Equal(int64(7)))
+		})
+		ginkgo.It("should round trip through SQL values", func() {
+			for _, original := range []*util.Optional{util.Of(int64(3)), util.Of("text"), util.OfEmpty()} {
+				value, err := original.Value()
+				gomega.Expect(err).To(gomega.BeNil())
+				scanned := util.Of("stale")
+				gomega.Expect(scanned.Scan(value)).To(gomega.Succeed())
+				gomega.Expect(scanned.IsPresent()).To(gomega.Equal(original.IsPresent()))
+				gomega.Expect(scanned.OrElse("none")).To(gomega.Equal(original.OrElse("none")))
+			}
+		})
+		ginkgo.It("should refuse to scan into a nil optional", func() {
+			var opt *util.Optional
+			gomega.Expect(opt.Scan(int64(1))).NotTo(gomega.Succeed())
+		})
+		ginkgo.It("should copy scanned bytes", func() {
+			buffer := []byte("abc")
+			opt := util.OfEmpty()
+			gomega.Expect(opt.Scan(buffer)).To(gomega.Succeed())
+			buffer[0] = 'x'
+			gomega.Expect(opt.Get()).To(gomega.Equal([]byte("abc")))
+		})
+	})
+})
@@ -0,0 +1,98 @@
+package stream_test
+
+import (
+	"errors"
+
+	"github.com/dynastywind/go-stream/optional"
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/dynastywind/go-stream/util"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+type node struct {
+	value int
+}
+
+var _ = ginkgo.Describe("Test typed optional", func() {
+	ginkgo.When("Tracking presence", func() {
+		ginkgo.It("should be empty as a zero value", func() {
+			var opt optional.Optional[int]
+			gomega.Expect(opt.IsPresent()).To(gomega.BeFalse())
+			gomega.Expect(opt.OrElse(3)).To(gomega.Equal(3))
+			gomega.Expect(func() { opt.Get() }).To(gomega.Panic())
+		})
+		ginkgo.It("should hold a present nil pointer", func() {
+			opt := optional.Of[*node](nil)
+			gomega.Expect(opt.IsPresent()).To(gomega.BeTrue())
+			gomega.Expect(opt.Get()).To(gomega.BeNil())
+		})
+		ginkgo.It("should hold a present zero value", func() {
+			opt := optional.Of(0)
+			gomega.Expect(opt.IsPresent()).To(gomega.BeTrue())
+			gomega.Expect(opt.OrElse(3)).To(gomega.Equal(0))
+		})
+	})
+	ginkgo.When("Falling back", func() {
+		ginkgo.It("should only call Or on an empty optional", func() {
+			fallback := func() optional.Optional[int] {
+				return optional.Of(2)
+			}
+			gomega.Expect(optional.Of(1).Or(fallback).Get()).To(gomega.Equal(1))
+			gomega.Expect(optional.Empty[int]().Or(fallback).Get()).To(gomega.Equal(2))
+		})
+		ginkgo.It("should return the given error on an empty optional", func() {
+			missing := errors.New("missing")
+			_, err := optional.Empty[string]().OrElseErr(missing)
+			gomega.Expect(err).To(gomega.Equal(missing))
+			value, err := optional.Of("a").OrElseErr(missing)
+			gomega.Expect(value).To(gomega.Equal("a"))
+			gomega.Expect(err).To(gomega.BeNil())
+		})
+		ginkgo.It("should call the matching branch of IfPresentOrElse", func() {
+			var calls []string
+			record := func(value string) {
+				calls = append(calls, value)
+			}
+			optional.Of("a").IfPresentOrElse(record, func() { record("empty") })
+			optional.Empty[string]().IfPresentOrElse(record, func() { record("empty") })
+			gomega.Expect(calls).To(gomega.Equal([]string{"a", "empty"}))
+		})
+	})
+	ginkgo.When("Transforming", func() {
+		ginkgo.It("should map and filter the value if present", func() {
+			length := optional.Map(optional.Of("abc"), func(s string) int { return len(s) })
+			gomega.Expect(length.Get()).To(gomega.Equal(3))
+			gomega.Expect(length.Filter(func(n int) bool { return n > 3 }).IsPresent()).To(gomega.BeFalse())
+			gomega.Expect(optional.Map(optional.Empty[string](), func(s string) int { return len(s) }).IsPresent()).To(gomega.BeFalse())
+		})
+		ginkgo.It("should stream zero or one item", func() {
+			gomega.Expect(optional.Of(5).Stream().ToArray()).To(gomega.Equal([]interface{}{5}))
+			gomega.Expect(optional.Empty[int]().Stream().Count()).To(gomega.Equal(0))
+		})
+	})
+	ginkgo.When("Comparing", func() {
+		ginkgo.It("should compare presence and values", func() {
+			gomega.Expect(optional.Of([]int{1}).Equal(optional.Of([]int{1}))).To(gomega.BeTrue())
+			gomega.Expect(optional.Of(1).Equal(optional.Of(2))).To(gomega.BeFalse())
+			gomega.Expect(optional.Of(0).Equal(optional.Empty[int]())).To(gomega.BeFalse())
+			gomega.Expect(optional.Empty[int]().Equal(optional.Empty[int]())).To(gomega.BeTrue())
+		})
+	})
+	ginkgo.When("Converting from and to untyped optionals", func() {
+		ginkgo.It("should convert stream results", func() {
+			opt := optional.FromOptional[int](stream.Of(3, 1, 2).Max(lessInt))
+			gomega.Expect(opt.Get()).To(gomega.Equal(3))
+			gomega.Expect(optional.FromOptional[int](stream.Of().FindFirst()).IsPresent()).To(gomega.BeFalse())
+		})
+		ginkgo.It("should panic on a value of another type", func() {
+			gomega.Expect(func() {
+				optional.FromOptional[string](util.Of(1))
+			}).To(gomega.Panic())
+		})
+		ginkgo.It("should convert back to an untyped optional", func() {
+			gomega.Expect(optional.Of(1).ToOptional().Get()).To(gomega.Equal(1))
+			gomega.Expect(optional.Empty[int]().ToOptional().IsPresent()).To(gomega.BeFalse())
+		})
+	})
+})
@@ -0,0 +1,144 @@
+package stream_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/dynastywind/go-stream/util"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	"github.com/onsi/gomega"
+)
+
+var functions = stream.Functions{
+	"isEven":  isEven,
+	"double":  double,
+	"lessInt": util.Comparator(lessInt),
+	"byTens":  byTens,
+	"hash": func(item interface{}) string {
+		return fmt.Sprint(item)
+	},
+	"now": func(interface{}) time.Time {
+		return time.Now()
+	},
+}
+
+func buildPipeline(definition string, source stream.Stream) (stream.Stream, error) {
+	def, err := stream.ParsePipeline(strings.NewReader(definition))
+	if err != nil {
+		return nil, err
+	}
+	return def.Build(source, functions)
+}
+
+var _ = ginkgo.Describe("Test pipeline definitions", func() {
+	ginkgo.When("Building a valid definition", func() {
+		ginkgo.It("should run the same operations as the equivalent method calls", func() {
+			s, err := buildPipeline(`{"steps": [
+				{"op": "FILTER", "params": [{"fn": "isEven"}]},
+				{"op": "MAP", "params": [{"fn": "double"}]},
+				{"op": "SORTED", "params": [{"fn": "lessInt"}]},
+				{"op": "SKIP", "params": [1]},
+				{"op": "LIMIT", "params": [3]}
+			]}`, stream.Of(8, 3, 6, 1, 4, 2, 10))
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(s.ToArray()).To(gomega.Equal([]interface{}{8, 12, 16}))
+		})
+		ginkgo.It("should switch to a parallel stream with the given number of go routines", func() {
+			s, err := buildPipeline(`{"parallel": 4, "steps": [
+				{"op": "MAP_ORDERED", "params": [{"fn": "double"}]},
+				{"op": "DISTINCT_WITHIN_TIME", "params": [{"fn": "key"}, {"fn": "now"}, "5m"]}
+			]}`, stream.Of(1, 2, 1))
+			gomega.Expect(err).To(gomega.MatchError(`steps[1].params[0]: function "key" is not registered`))
+			gomega.Expect(s).To(gomega.BeNil())
+			s, err = buildPipeline(`{"parallel": 4, "steps": [{"op": "MAP_ORDERED", "params": [{"fn": "double"}]}]}`, stream.Of(1, 2, 3))
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(s.Explain().Routines).To(gomega.Equal(4))
+			gomega.Expect(s.ToArray()).To(gomega.Equal([]interface{}{2, 4, 6}))
+		})
+		ginkgo.It("should hand scalars and functions over to registered operations", func() {
+			s, err := buildPipeline(`{"steps": [{"op": "SCALE", "params": [3]}, {"op": "RUNNING_TOTAL"}]}`, stream.Of(1, 2, 3))
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(s.ToArray()).To(gomega.Equal([]interface{}{3, 9, 18}))
+		})
+		ginkgo.It("should build definitions decoded from YAML", func() {
+			def := &stream.PipelineDefinition{Steps: []stream.StepDefinition{
+				{Op: "SORTED", Params: []interface{}{map[interface{}]interface{}{"fn": "byTens"}}},
+				{Op: "TOP_K", Params: []interface{}{2, map[interface{}]interface{}{"fn": "lessInt"}}},
+			}}
+			s, err := def.Build(stream.Of(21, 3, 15), functions)
+			gomega.Expect(err).To(gomega.BeNil())
+			gomega.Expect(s.ToArray()).To(gomega.Equal([]interface{}{21, 15}))
+		})
+		ginkgo.It("should seed the random source so that sampling is reproducible", func() {
+			definition := `{"seed": 42, "steps": [{"op": "SHUFFLE"}]}`
+			first, err := buildPipeline(definition, stream.Of(ints(20)...))
+			gomega.Expect(err).To(gomega.BeNil())
+			second, _ := buildPipeline(definition, stream.Of(ints(20)...))
+			gomega.Expect(first.ToArray()).To(gomega.Equal(second.ToArray()))
+		})
+	})
+
+	ginkgo.It("should describe the parameters of every built-in operation", func() {
+		for _, tag := range stream.Tags() {
+			if tag == SCALE || tag == RUNNING_TOTAL {
+				continue
+			}
+			_, err := (&stream.PipelineDefinition{Steps: []stream.StepDefinition{{Op: string(tag)}}}).Build(stream.Of(), functions)
+			if err != nil {
+				var pipelineErr *stream.PipelineError
+				gomega.Expect(errors.As(err, &pipelineErr)).To(gomega.BeTrue())
+				gomega.Expect(pipelineErr.Path).To(gomega.Equal("steps[0].params"), string(tag))
+			}
+		}
+	})
+
+	table.DescribeTable("Rejecting an invalid definition",
+		func(definition, expected string) {
+			s, err := buildPipeline(definition, stream.Of(1, 2, 3))
+			gomega.Expect(s).To(gomega.BeNil())
+			gomega.Expect(err).To(gomega.MatchError(expected))
+		},
+		table.Entry("with an unknown operation", `{"steps": [{"op": "LIMIT", "params": [1]}, {"op": "FILTR"}]}`,
+			`steps[1].op: unknown operation "FILTR"`),
+		table.Entry("with missing parameters", `{"steps": [{"op": "TOP_K", "params": [3]}]}`,
+			`steps[0].params: TOP_K takes 2 parameters, got 1`),
+		table.Entry("with too many parameters", `{"steps": [{"op": "SORTED", "params": [{"fn": "lessInt"}, {"fn": "lessInt"}, 1]}]}`,
+			`steps[0].params: SORTED takes 1 to 2 parameters, got 3`),
+		table.Entry("with a fractional integer", `{"steps": [{"op": "LIMIT", "params": [1.5]}]}`,
+			`steps[0].params[0]: expected an integer, got number 1.5`),
+		table.Entry("with a string instead of a number", `{"steps": [{"op": "SAMPLE_FRACTION", "params": ["half"]}]}`,
+			`steps[0].params[0]: expected a number, got string "half"`),
+		table.Entry("with a bare function name", `{"steps": [{"op": "FILTER", "params": ["isEven"]}]}`,
+			`steps[0].params[0]: expected a function reference like {"fn": "name"} or {"expr": "..."}, got string "isEven"`),
+		table.Entry("with a function of the wrong type", `{"steps": [{"op": "FILTER", "params": [{"fn": "double"}]}]}`,
+			`steps[0].params[0]: function "double" is a func(interface {}) interface {}, expected func(interface {}) bool`),
+		table.Entry("with a function which is not a sorter", `{"steps": [{"op": "SORTED", "params": [{"fn": "lessInt"}, {"fn": "isEven"}]}]}`,
+			`steps[0].params[1]: function "isEven" is a func(interface {}) bool, expected util.Sorter`),
+		table.Entry("with a negative limit", `{"steps": [{"op": "LIMIT", "params": [-1]}]}`,
+			`steps[0].params[0]: should not be negative, got -1`),
+		table.Entry("with a negative skip", `{"steps": [{"op": "MAP", "params": [{"fn": "double"}]}, {"op": "SKIP", "params": [-1]}]}`,
+			`steps[1].params[0]: should not be negative, got -1`),
+		table.Entry("with a negative sample size", `{"steps": [{"op": "SAMPLE", "params": [-3]}]}`,
+			`steps[0].params[0]: should not be negative, got -3`),
+		table.Entry("with a negative number of top items", `{"steps": [{"op": "TOP_K", "params": [-2, {"fn": "lessInt"}]}]}`,
+			`steps[0].params[0]: should not be negative, got -2`),
+		table.Entry("with a sample fraction above 1", `{"steps": [{"op": "SAMPLE_FRACTION", "params": [5]}]}`,
+			`steps[0].params[0]: should be between 0 and 1, got 5`),
+		table.Entry("with a false positive rate of 2", `{"steps": [{"op": "DISTINCT_APPROX", "params": [{"fn": "hash"}, 100, 2]}]}`,
+			`steps[0].params[2]: should be between 0 and 1 exclusive, got 2`),
+		table.Entry("with an empty Bloom filter", `{"steps": [{"op": "DISTINCT_APPROX", "params": [{"fn": "hash"}, 0, 0.01]}]}`,
+			`steps[0].params[1]: should be positive, got 0`),
+		table.Entry("with an empty window", `{"steps": [{"op": "DISTINCT_WITHIN", "params": [{"fn": "hash"}, 0]}]}`,
+			`steps[0].params[1]: should be positive, got 0`),
+		table.Entry("with a negative time window", `{"steps": [{"op": "DISTINCT_WITHIN_TIME", "params": [{"fn": "hash"}, {"fn": "now"}, "-5m"]}]}`,
+			`steps[0].params[2]: should be positive, got -5m0s`),
+		table.Entry("with a negative number of go routines", `{"parallel": -1, "steps": []}`,
+			`parallel: number of go routines should not be negative, got -1`),
+		table.Entry("with an unknown field", `{"steps": [], "sequential": true}`,
+			`invalid pipeline definition: json: unknown field "sequential"`),
+	)
+})
@@ -0,0 +1,70 @@
+package stream_test
+
+import (
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+func isEven(item interface{}) bool {
+	return item.(int)%2 == 0
+}
+
+func double(item interface{}) interface{} {
+	return item.(int) * 2
+}
+
+var _ = ginkgo.Describe("Test pipeline plans", func() {
+	ginkgo.When("Explaining a parallel pipeline", func() {
+		plan := stream.OfParallel(4, 1, 2, 3).Filter(isEven).MapOrdered(double).Sorted(lessInt).Limit(10).Unoptimized().Explain()
+		ginkgo.It("should describe every stage", func() {
+			gomega.Expect(plan.Parallel).To(gomega.BeTrue())
+			gomega.Expect(plan.Routines).To(gomega.Equal(4))
+			gomega.Expect(plan.Stages).To(gomega.Equal([]stream.Stage{
+				{Tag: stream.FILTER, Ordered: false, Stateful: false},
+				{Tag: stream.MAP_ORDERED, Ordered: true, Stateful: false},
+				{Tag: stream.SORTED, Ordered: true, Stateful: true},
+				{Tag: stream.LIMIT, Args: []interface{}{10}, Ordered: true, Stateful: true},
+			}))
+		})
+		ginkgo.It("should print one stage per line", func() {
+			gomega.Expect(plan.String()).To(gomega.Equal("parallel stream with 4 routines\n" +
+				"1. FILTER [stateless, unordered]\n" +
+				"2. MAP_ORDERED [stateless, ordered]\n" +
+				"3. SORTED [stateful, ordered]\n" +
+				"4. LIMIT(10) [stateful, ordered]"))
+		})
+		ginkgo.It("should render a Graphviz graph", func() {
+			gomega.Expect(plan.DOT()).To(gomega.Equal("digraph pipeline {\n" +
+				"\trankdir=LR;\n" +
+				"\tsource [shape=box, label=\"parallel stream with 4 routines\"];\n" +
+				"\tstage1 [label=\"FILTER\\nstateless, unordered\"];\n" +
+				"\tsource -> stage1;\n" +
+				"\tstage2 [label=\"MAP_ORDERED\\nstateless, ordered\"];\n" +
+				"\tstage1 -> stage2;\n" +
+				"\tstage3 [label=\"SORTED\\nstateful, ordered\", style=bold];\n" +
+				"\tstage2 -> stage3;\n" +
+				"\tstage4 [label=\"LIMIT(10)\\nstateful, ordered\", style=bold];\n" +
+				"\tstage3 -> stage4;\n" +
+				"}\n"))
+		})
+	})
+	ginkgo.When("Explaining a sequential pipeline", func() {
+		ginkgo.It("should keep every stage ordered but shuffling", func() {
+			plan := stream.Of(1, 2, 3).Filter(isEven).SampleFraction(0.5).Shuffle().Explain()
+			gomega.Expect(plan.String()).To(gomega.Equal("sequential stream\n" +
+				"1. FILTER [stateless, ordered]\n" +
+				"2. SAMPLE_FRACTION(0.5) [stateless, ordered]\n" +
+				"3. SHUFFLE [stateful, unordered]"))
+		})
+		ginkgo.It("should describe an empty pipeline", func() {
+			plan := stream.Of(1).Explain()
+			gomega.Expect(plan.Routines).To(gomega.Equal(1))
+			gomega.Expect(plan.Stages).To(gomega.BeEmpty())
+		})
+		ginkgo.It("should follow conversion to a parallel stream", func() {
+			plan := stream.Of(1, 2).Map(double).AsParallel(2).Explain()
+			gomega.Expect(plan.String()).To(gomega.Equal("parallel stream with 2 routines\n1. MAP [stateless, unordered]"))
+		})
+	})
+})
@@ -0,0 +1,98 @@
+package stream_test
+
+import (
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+const (
+	SCALE         stream.OperationTag = "SCALE"
+	RUNNING_TOTAL stream.OperationTag = "RUNNING_TOTAL"
+)
+
+var parallelScales int
+
+func scale(arr []interface{}, params []interface{}) []interface{} {
+	result := make([]interface{}, len(arr))
+	for i, item := range arr {
+		result[i] = item.(int) * params[0].(int)
+	}
+	return result
+}
+
+func init() {
+	stream.Register(stream.Operation{
+		Tag:        SCALE,
+		Sequential: scale,
+		Parallel: func(arr []interface{}, routines int, params []interface{}) []interface{} {
+			parallelScales++
+			return scale(arr, params)
+		},
+	})
+	stream.Register(stream.Operation{
+		Tag: RUNNING_TOTAL,
+		Sequential: func(arr []interface{}, params []interface{}) []interface{} {
+			result := make([]interface{}, len(arr))
+			total := 0
+			for i, item := range arr {
+				total += item.(int)
+				result[i] = total
+			}
+			return result
+		},
+		Stateful: true,
+	})
+	pipelines[SCALE] = func(s stream.Stream) stream.Stream {
+		return s.Apply(SCALE, 3)
+	}
+	pipelines[RUNNING_TOTAL] = func(s stream.Stream) stream.Stream {
+		return s.Apply(RUNNING_TOTAL)
+	}
+}
+
+var _ = ginkgo.Describe("Test user-defined operations", func() {
+	ginkgo.When("Applying a registered operation", func() {
+		ginkgo.It("should run the sequential implementation in a sequential stream", func() {
+			parallelScales = 0
+			arr := stream.Of(1, 2, 3).Apply(SCALE, 2).Apply(RUNNING_TOTAL).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{2, 6, 12}))
+			gomega.Expect(parallelScales).To(gomega.Equal(0))
+		})
+		ginkgo.It("should run the parallel implementation in a parallel stream", func() {
+			parallelScales = 0
+			arr := stream.Of(1, 2, 3).Apply(SCALE, 2).AsParallel(2).Apply(RUNNING_TOTAL).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{2, 6, 12}))
+			gomega.Expect(parallelScales).To(gomega.Equal(1))
+		})
+		ginkgo.It("should be described like built-in operations", func() {
+			plan := stream.OfParallel(2, 1, 2).Apply(SCALE, 2).Apply(RUNNING_TOTAL).Explain()
+			gomega.Expect(plan.String()).To(gomega.Equal("parallel stream with 2 routines\n" +
+				"1. SCALE(2) [stateless, ordered]\n" +
+				"2. RUNNING_TOTAL [stateful, ordered]"))
+		})
+		ginkgo.It("should be listed with built-in tags", func() {
+			gomega.Expect(stream.Tags()).To(gomega.ContainElements(SCALE, RUNNING_TOTAL, stream.MAP))
+		})
+	})
+	ginkgo.When("Misusing the registry", func() {
+		ginkgo.It("should panic on an unregistered tag", func() {
+			gomega.Expect(func() {
+				stream.Of(1).Apply("UNKNOWN")
+			}).To(gomega.PanicWith("Unregistered operation type found: UNKNOWN"))
+		})
+		ginkgo.It("should panic on a tag already taken", func() {
+			gomega.Expect(func() {
+				stream.Register(stream.Operation{Tag: SCALE, Sequential: scale})
+			}).To(gomega.PanicWith("Operation type SCALE is already registered"))
+			gomega.Expect(func() {
+				stream.Register(stream.Operation{Tag: stream.MAP, Sequential: scale})
+			}).To(gomega.PanicWith("Operation type MAP is built in"))
+		})
+		ginkgo.It("should panic without a sequential implementation", func() {
+			gomega.Expect(func() {
+				stream.Register(stream.Operation{Tag: "NOTHING"})
+			}).To(gomega.Panic())
+		})
+	})
+})
@@ -0,0 +1,114 @@
+package stream_test
+
+import (
+	"math/rand"
+	"sort"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+func ints(n int) []interface{} {
+	arr := make([]interface{}, n)
+	for i := range arr {
+		arr[i] = i
+	}
+	return arr
+}
+
+func seeded() *rand.Rand {
+	return rand.New(rand.NewSource(2021))
+}
+
+func isAscending(arr []interface{}) bool {
+	return sort.SliceIsSorted(arr, func(i, j int) bool {
+		return arr[i].(int) < arr[j].(int)
+	})
+}
+
+var _ = ginkgo.Describe("Test random operations", func() {
+	ginkgo.When("Executing Sample", func() {
+		ginkgo.It("should keep k distinct items in encounter order", func() {
+			arr := stream.Of(ints(1000)...).WithRandom(seeded()).Sample(10).ToArray()
+			gomega.Expect(len(arr)).To(gomega.Equal(10))
+			gomega.Expect(isAscending(arr)).To(gomega.BeTrue())
+			gomega.Expect(stream.FromArray(arr).DistinctBy(func(item interface{}) interface{} {
+				return item
+			}).Count()).To(gomega.Equal(10))
+		})
+		ginkgo.It("should be reproducible with the same seed", func() {
+			first := stream.Of(ints(1000)...).WithRandom(seeded()).Sample(10).ToArray()
+			second := stream.OfParallel(4, ints(1000)...).WithRandom(seeded()).Sample(10).ToArray()
+			gomega.Expect(second).To(gomega.Equal(first))
+		})
+		ginkgo.It("should keep every item if k is greater than stream length", func() {
+			arr := stream.Of(1, 2, 3).Sample(5).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{1, 2, 3}))
+		})
+		ginkgo.It("should pick every item with the same probability", func() {
+			counts := make([]int, 10)
+			random := seeded()
+			for i := 0; i < 5000; i++ {
+				for _, item := range stream.Of(ints(10)...).WithRandom(random).Sample(2).ToArray() {
+					counts[item.(int)]++
+				}
+			}
+			for _, count := range counts {
+				gomega.Expect(count).To(gomega.BeNumerically("~", 1000, 100))
+			}
+		})
+	})
+	ginkgo.When("Executing SampleFraction", func() {
+		ginkgo.It("should keep about the given fraction of items in encounter order", func() {
+			arr := stream.Of(ints(10000)...).WithRandom(seeded()).SampleFraction(0.2).ToArray()
+			gomega.Expect(len(arr)).To(gomega.BeNumerically("~", 2000, 150))
+			gomega.Expect(isAscending(arr)).To(gomega.BeTrue())
+		})
+		ginkgo.It("should panic on a fraction out of range", func() {
+			gomega.Expect(func() {
+				stream.Of(1).SampleFraction(1.5).ToArray()
+			}).To(gomega.Panic())
+		})
+	})
+	ginkgo.When("Executing Shuffle", func() {
+		ginkgo.It("should keep every item", func() {
+			arr := stream.OfParallel(2, ints(100)...).Shuffle().Sorted(lessInt).ToArray()
+			gomega.Expect(arr).To(gomega.Equal(ints(100)))
+		})
+		ginkgo.It("should be reproducible with the same seed", func() {
+			first := stream.Of(ints(100)...).WithRandom(seeded()).Shuffle().ToArray()
+			second := stream.Of(ints(100)...).WithRandom(seeded()).Shuffle().ToArray()
+			gomega.Expect(second).To(gomega.Equal(first))
+			gomega.Expect(first).NotTo(gomega.Equal(ints(100)))
+		})
+	})
+	ginkgo.When("Executing FindAny with a random source", func() {
+		ginkgo.It("should be reproducible with the same seed", func() {
+			first := stream.Of(ints(100)...).WithRandom(seeded()).FindAny().Get()
+			second := stream.Of(ints(100)...).WithRandom(seeded()).FindAny().Get()
+			gomega.Expect(second).To(gomega.Equal(first))
+		})
+	})
+	ginkgo.When("Converting a stream with a random source", func() {
+		ginkgo.It("should keep the random source", func() {
+			first := stream.Of(ints(100)...).WithRandom(seeded()).Shuffle().ToArray()
+			second := stream.Of(ints(100)...).WithRandom(seeded()).Shuffle().AsParallel(3).ToArray()
+			gomega.Expect(second).To(gomega.Equal(first))
+		})
+	})
+	ginkgo.When("Setting a random source after random operations", func() {
+		ginkgo.It("should use it in operations added before", func() {
+			first := stream.Of(ints(100)...).WithRandom(seeded()).Sample(10).Shuffle().ToArray()
+			second := stream.Of(ints(100)...).Sample(10).Shuffle().WithRandom(seeded()).ToArray()
+			gomega.Expect(second).To(gomega.Equal(first))
+			first = stream.OfParallel(3, ints(100)...).WithRandom(seeded()).SampleFraction(0.5).ToArray()
+			second = stream.OfParallel(3, ints(100)...).SampleFraction(0.5).WithRandom(seeded()).ToArray()
+			gomega.Expect(second).To(gomega.Equal(first))
+		})
+		ginkgo.It("should not show up as a stage", func() {
+			s := stream.Of(ints(10)...).Shuffle().WithRandom(seeded())
+			gomega.Expect(stageTags(s)).To(gomega.Equal([]stream.OperationTag{stream.SHUFFLE}))
+		})
+	})
+})
@@ -0,0 +1,140 @@
+package stream_test
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/dynastywind/go-stream/util"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+func shuffledInts(n int) []interface{} {
+	arr := make([]interface{}, n)
+	for i, j := range rand.New(rand.NewSource(42)).Perm(n) {
+		arr[i] = j + 1
+	}
+	return arr
+}
+
+var _ = ginkgo.Describe("Test approximate collectors", func() {
+	ginkgo.When("Executing CountDistinctApprox", func() {
+		ginkgo.It("should estimate the number of unique items", func() {
+			arr := shuffledInts(100000)
+			arr = append(arr, arr[:50000]...)
+			count := stream.Of(arr...).Collect(stream.CountDistinctApprox(itoa, 12)).(uint64)
+			gomega.Expect(float64(count)).To(gomega.BeNumerically("~", 100000, 5000))
+		})
+		ginkgo.It("should give the same estimate when merging parallel sketches", func() {
+			arr := shuffledInts(20000)
+			sequential := stream.Of(arr...).Collect(stream.CountDistinctApprox(itoa, 10))
+			parallel := stream.OfParallel(4, arr...).Collect(stream.CountDistinctApprox(itoa, 10))
+			gomega.Expect(parallel).To(gomega.Equal(sequential))
+		})
+		ginkgo.It("should count small cardinalities almost exactly", func() {
+			count := stream.Of(1, 2, 3, 2, 1).Collect(stream.CountDistinctApprox(itoa, 12))
+			gomega.Expect(count).To(gomega.Equal(uint64(3)))
+		})
+	})
+	ginkgo.When("Executing Quantiles", func() {
+		ginkgo.It("should estimate quantiles of a large stream", func() {
+			qs := stream.OfParallel(4, shuffledInts(100000)...).Collect(stream.Quantiles(0, 0.1, 0.5, 0.99, 1)).([]float64)
+			gomega.Expect(qs[0]).To(gomega.Equal(1.0))
+			gomega.Expect(qs[1]).To(gomega.BeNumerically("~", 10000, 2000))
+			gomega.Expect(qs[2]).To(gomega.BeNumerically("~", 50000, 2000))
+			gomega.Expect(qs[3]).To(gomega.BeNumerically("~", 99000, 2000))
+			gomega.Expect(qs[4]).To(gomega.Equal(100000.0))
+		})
+		ginkgo.It("should be exact on small streams", func() {
+			qs := stream.Of(3, 1.5, 2, uint8(4)).Collect(stream.Quantiles(0.5)).([]float64)
+			gomega.Expect(qs).To(gomega.Equal([]float64{2}))
+		})
+		ginkgo.It("should return NaN for an empty stream", func() {
+			qs := stream.Of().Collect(stream.Quantiles(0.5)).([]float64)
+			gomega.Expect(math.IsNaN(qs[0])).To(gomega.BeTrue())
+		})
+	})
+	ginkgo.When("Executing FrequentItems", func() {
+		ginkgo.It("should find the most frequent items among many rare ones", func() {
+			arr := shuffledInts(5000)
+			for i := 0; i < 1000; i++ {
+				arr = append(arr, "a")
+			}
+			for i := 0; i < 500; i++ {
+				arr = append(arr, "b")
+			}
+			rand.New(rand.NewSource(7)).Shuffle(len(arr), func(i, j int) {
+				arr[i], arr[j] = arr[j], arr[i]
+			})
+			top := stream.OfParallel(3, arr...).Collect(stream.FrequentItems(50, 500)).([]util.ItemCount)
+			gomega.Expect(len(top)).To(gomega.Equal(50))
+			gomega.Expect(top[0].Item).To(gomega.Equal("a"))
+			gomega.Expect(top[0].Count - top[0].Error).To(gomega.BeNumerically("<=", 1000))
+			gomega.Expect(top[0].Count).To(gomega.BeNumerically(">=", 1000))
+			gomega.Expect(top[1].Item).To(gomega.Equal("b"))
+		})
+		ginkgo.It("should recover the most frequent items of skewed data", func() {
+			zipf := rand.NewZipf(rand.New(rand.NewSource(11)), 1.2, 1, 100000)
+			arr := make([]interface{}, 100000)
+			counts := make(map[interface{}]int)
+			for i := range arr {
+				arr[i] = int(zipf.Uint64())
+				counts[arr[i]]++
+			}
+			expected := stream.Of(stream.Of(arr...).Distinct(func(item interface{}) string {
+				return fmt.Sprint(item)
+			}).ToArray()...).TopK(5, func(a, b interface{}) bool {
+				return counts[a] < counts[b]
+			}).ToArray()
+			for _, s := range []stream.Stream{stream.Of(arr...), stream.OfParallel(4, arr...)} {
+				top := s.Collect(stream.FrequentItems(5, 50)).([]util.ItemCount)
+				items := make([]interface{}, len(top))
+				for i, count := range top {
+					items[i] = count.Item
+					gomega.Expect(count.Count).To(gomega.BeNumerically(">=", counts[count.Item]))
+					gomega.Expect(count.Count - count.Error).To(gomega.BeNumerically("<=", counts[count.Item]))
+				}
+				gomega.Expect(items).To(gomega.Equal(expected))
+			}
+		})
+		ginkgo.It("should reject more items than counters", func() {
+			gomega.Expect(func() {
+				stream.FrequentItems(10, 5)
+			}).To(gomega.Panic())
+		})
+		ginkgo.It("should count exactly when there are fewer items than counters", func() {
+			top := stream.Of("x", "y", "x", "z", "x", "y").Collect(stream.FrequentItems(5, 5)).([]util.ItemCount)
+			gomega.Expect(top).To(gomega.Equal([]util.ItemCount{{Item: "x", Count: 3}, {Item: "y", Count: 2}, {Item: "z", Count: 1}}))
+		})
+		ginkgo.It("should replace the item tracked first among the least counted ones", func() {
+			for i := 0; i < 20; i++ {
+				summary := util.NewSpaceSaving(2)
+				for _, item := range []string{"a", "b", "c"} {
+					summary.Add(item)
+				}
+				gomega.Expect(summary.Top()).To(gomega.Equal([]util.ItemCount{{Item: "c", Count: 2, Error: 1}, {Item: "b", Count: 1}}))
+			}
+		})
+		ginkgo.It("should add the least count of the other summary to items it does not track when merging", func() {
+			left, right := util.NewSpaceSaving(2), util.NewSpaceSaving(2)
+			for _, item := range []string{"x", "x", "x", "y"} {
+				left.Add(item)
+			}
+			for _, item := range []string{"z", "z", "y"} {
+				right.Add(item)
+			}
+			left.Merge(right)
+			gomega.Expect(left.Top()).To(gomega.Equal([]util.ItemCount{{Item: "x", Count: 4, Error: 1}, {Item: "z", Count: 3, Error: 1}}))
+		})
+		ginkgo.It("should merge exactly when there are fewer items than counters", func() {
+			left, right := util.NewSpaceSaving(3), util.NewSpaceSaving(3)
+			left.Add("x")
+			right.Add("y")
+			right.Add("x")
+			left.Merge(right)
+			gomega.Expect(left.Top()).To(gomega.Equal([]util.ItemCount{{Item: "x", Count: 2}, {Item: "y", Count: 1}}))
+		})
+	})
+})
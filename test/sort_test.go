@@ -0,0 +1,139 @@
+package stream_test
+
+import (
+	"math/rand"
+	"reflect"
+	"sort"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/dynastywind/go-stream/util"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/ginkgo/extensions/table"
+	"github.com/onsi/gomega"
+)
+
+// sortItem carries an index to check whether equal keys keep their original order
+type sortItem struct {
+	key   int
+	index int
+}
+
+func lessByKey(a, b interface{}) bool {
+	return a.(sortItem).key < b.(sortItem).key
+}
+
+func sortItems(r *rand.Rand, length int, keys int, nearlySorted bool) []interface{} {
+	arr := make([]interface{}, length)
+	for i := range arr {
+		var key int
+		if !nearlySorted {
+			key = r.Intn(keys) - keys/2
+		} else if key = i; r.Intn(20) == 0 {
+			key = r.Intn(length)
+		}
+		arr[i] = sortItem{key: key, index: i}
+	}
+	return arr
+}
+
+func expectSorted(original, sorted []interface{}, stable bool) {
+	expected := make([]interface{}, len(original))
+	copy(expected, original)
+	sort.SliceStable(expected, func(i, j int) bool {
+		return lessByKey(expected[i], expected[j])
+	})
+	if stable {
+		gomega.Expect(sorted).To(gomega.Equal(expected))
+		return
+	}
+	gomega.Expect(sort.SliceIsSorted(sorted, func(i, j int) bool {
+		return lessByKey(sorted[i], sorted[j])
+	})).To(gomega.BeTrue())
+	// Equal items may have been reordered, which is undone before checking no item is lost
+	restored := make([]interface{}, len(sorted))
+	copy(restored, sorted)
+	sort.Slice(restored, func(i, j int) bool {
+		a, b := restored[i].(sortItem), restored[j].(sortItem)
+		return a.key < b.key || a.key == b.key && a.index < b.index
+	})
+	gomega.Expect(restored).To(gomega.Equal(expected))
+}
+
+var _ = ginkgo.Describe("Test sorting algorithms", func() {
+	radixSorter := util.RadixSorter(func(item interface{}) int64 {
+		return int64(item.(sortItem).key)
+	})
+	table.DescribeTable("Sorting data items",
+		func(sorter util.Sorter, stable bool) {
+			r := rand.New(rand.NewSource(42))
+			for _, length := range []int{0, 1, 2, 11, 100, 1000, 5000, 20000} {
+				for _, keys := range []int{3, 1 << 20} {
+					original := sortItems(r, length, keys, false)
+					arr := make([]interface{}, length)
+					copy(arr, original)
+					expectSorted(original, sorter.Sort(arr, lessByKey), stable)
+				}
+				original := sortItems(r, length, 0, true)
+				arr := make([]interface{}, length)
+				copy(arr, original)
+				expectSorted(original, sorter.Sort(arr, lessByKey), stable)
+
+				reversed := make([]interface{}, length)
+				for i := range reversed {
+					reversed[i] = sortItem{key: length - i, index: i}
+				}
+				arr = make([]interface{}, length)
+				copy(arr, reversed)
+				expectSorted(reversed, sorter.Sort(arr, lessByKey), stable)
+			}
+		},
+		table.Entry("with heap sort", util.HeapSorter, false),
+		table.Entry("with merge sort", util.MergeSorter, true),
+		table.Entry("with pdqsort", util.PdqSorter, false),
+		table.Entry("with timsort", util.TimSorter, true),
+		table.Entry("with radix sort", radixSorter, true),
+		table.Entry("with parallel merge sort on a single go routine", util.ParallelMergeSorter(1), true),
+		table.Entry("with parallel merge sort on 3 go routines", util.ParallelMergeSorter(3), true),
+		table.Entry("with parallel merge sort on 8 go routines", util.ParallelMergeSorter(8), true),
+	)
+
+	ginkgo.When("Executing MergeSort", func() {
+		ginkgo.It("should leave its input untouched", func() {
+			original := sortItems(rand.New(rand.NewSource(3)), 5000, 50, false)
+			arr := make([]interface{}, len(original))
+			copy(arr, original)
+			expectSorted(original, util.MergeSort(arr, lessByKey), true)
+			gomega.Expect(arr).To(gomega.Equal(original))
+		})
+	})
+
+	ginkgo.Context("Sorting streams", func() {
+		ginkgo.When("Executing Sorted on a sequential stream", func() {
+			ginkgo.It("should keep the order of equal items", func() {
+				arr := stream.Of(sortItem{2, 0}, sortItem{1, 1}, sortItem{2, 2}, sortItem{1, 3}).Sorted(lessByKey).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{sortItem{1, 1}, sortItem{1, 3}, sortItem{2, 0}, sortItem{2, 2}}))
+			})
+		})
+		ginkgo.When("Executing Sorted on a parallel stream", func() {
+			ginkgo.It("should keep the order of equal items", func() {
+				original := sortItems(rand.New(rand.NewSource(7)), 10000, 100, false)
+				arr := stream.OfParallel(4, original...).Sorted(lessByKey).ToArray()
+				expectSorted(original, arr, true)
+			})
+		})
+		ginkgo.When("Executing SortedWith", func() {
+			ginkgo.It("should sort with the given sorter", func() {
+				arr := stream.Of(4, 2, 1, 3).SortedWith(func(a, b interface{}) bool {
+					return a.(int) < b.(int)
+				}, util.TimSorter).ToTypedArray(reflect.TypeOf(1)).Interface().([]int)
+				gomega.Expect(arr).To(gomega.Equal([]int{1, 2, 3, 4}))
+			})
+			ginkgo.It("should keep the sorter when converting to a parallel stream", func() {
+				arr := stream.Of(-4, 2, -1, 3).SortedWith(nil, util.RadixSorter(func(item interface{}) int64 {
+					return int64(item.(int))
+				})).AsParallel(2).ToTypedArray(reflect.TypeOf(1)).Interface().([]int)
+				gomega.Expect(arr).To(gomega.Equal([]int{-4, -1, 2, 3}))
+			})
+		})
+	})
+})
@@ -0,0 +1,208 @@
+package stream_test
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"encoding/base64"
+	"io"
+	"io/ioutil"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// A tar archive holding a.txt with "hello", compressed by bzip2
+const bzip2Tar = "QlpoOTFBWSZTWTVN9ngAAHL7hMkAAEJAAX+AAAhiRJ5AAACACCAAdQ1TZEPUMhso0NqCSKaNA0aAaBT5rmdCDqACRPOJGbVmLJIHTD0fiqCIsANIBBzE0I8SlQqqyHALWz+ehR0GS14vB7vTPBIPxdyRThQkDVN9ngA="
+
+var archiveFiles = []struct {
+	name    string
+	content string
+}{
+	{"a.txt", "hello"},
+	{"dir/b.log", "world!"},
+	{"c.txt", "stream"},
+}
+
+func buildTar(compressed bool) []byte {
+	var buf bytes.Buffer
+	var w io.Writer = &buf
+	var gz *gzip.Writer
+	if compressed {
+		gz = gzip.NewWriter(&buf)
+		w = gz
+	}
+	tw := tar.NewWriter(w)
+	for _, file := range archiveFiles {
+		tw.WriteHeader(&tar.Header{Name: file.name, Mode: 0644, Size: int64(len(file.content))})
+		tw.Write([]byte(file.content))
+	}
+	tw.Close()
+	if gz != nil {
+		gz.Close()
+	}
+	return buf.Bytes()
+}
+
+func buildZip() []byte {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range archiveFiles {
+		w, _ := zw.Create(file.name)
+		w.Write([]byte(file.content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func opener(data []byte) func() (io.ReadCloser, error) {
+	return func() (io.ReadCloser, error) {
+		return ioutil.NopCloser(bytes.NewReader(data)), nil
+	}
+}
+
+// archiveOpener counts how many times an archive is opened and how many bytes are read from it
+type archiveOpener struct {
+	data     []byte
+	seekable bool
+	opened   int
+	read     int
+}
+
+func countingOpener(data []byte, seekable bool) *archiveOpener {
+	return &archiveOpener{data: data, seekable: seekable}
+}
+
+func (o *archiveOpener) open() (io.ReadCloser, error) {
+	o.opened++
+	file := &archiveFile{Reader: bytes.NewReader(o.data), opener: o}
+	if o.seekable {
+		return file, nil
+	}
+	return struct {
+		io.Reader
+		io.Closer
+	}{file, file}, nil
+}
+
+type archiveFile struct {
+	*bytes.Reader
+	opener *archiveOpener
+}
+
+func (f *archiveFile) Read(p []byte) (int, error) {
+	n, err := f.Reader.Read(p)
+	f.opener.read += n
+	return n, err
+}
+
+func (f *archiveFile) Close() error {
+	return nil
+}
+
+func entryContent(item interface{}) interface{} {
+	r, err := item.(stream.ArchiveEntry).Open()
+	gomega.Expect(err).To(gomega.BeNil())
+	defer r.Close()
+	content, err := ioutil.ReadAll(r)
+	gomega.Expect(err).To(gomega.BeNil())
+	return string(content)
+}
+
+func entryName(item interface{}) interface{} {
+	return item.(stream.ArchiveEntry).Name
+}
+
+func isTxt(item interface{}) bool {
+	name := item.(stream.ArchiveEntry).Name
+	return name[len(name)-4:] == ".txt"
+}
+
+var _ = ginkgo.Describe("Test archive sources", func() {
+	ginkgo.Context("Tar source test", func() {
+		ginkgo.When("Reading a plain tar archive", func() {
+			ginkgo.It("should list entries with metadata", func() {
+				arr := stream.FromTar(opener(buildTar(false)), stream.TarOptions{}).Map(func(item interface{}) interface{} {
+					entry := item.(stream.ArchiveEntry)
+					return []interface{}{entry.Name, entry.Size, entry.IsDir()}
+				}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{
+					[]interface{}{"a.txt", int64(5), false},
+					[]interface{}{"dir/b.log", int64(6), false},
+					[]interface{}{"c.txt", int64(6), false},
+				}))
+			})
+		})
+		ginkgo.When("Reading a gzip compressed tar archive", func() {
+			ginkgo.It("should open filtered entries lazily", func() {
+				arr := stream.FromTar(opener(buildTar(true)), stream.TarOptions{}).Filter(isTxt).Map(entryContent).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{"hello", "stream"}))
+			})
+		})
+		ginkgo.When("Buffering entries of a compressed tar archive", func() {
+			ginkgo.It("should only read the archive again for entries beyond the buffer size", func() {
+				for size, expected := range map[int64]int{0: 4, 5: 3, 1 << 20: 1} {
+					open := countingOpener(buildTar(true), false)
+					arr := stream.FromTar(open.open, stream.TarOptions{BufferSize: size}).Map(entryContent).ToArray()
+					gomega.Expect(arr).To(gomega.Equal([]interface{}{"hello", "world!", "stream"}))
+					gomega.Expect(open.opened).To(gomega.Equal(expected), "buffer size %d", size)
+				}
+			})
+		})
+		ginkgo.When("Reading an uncompressed tar archive which can seek", func() {
+			ginkgo.It("should seek to entry contents", func() {
+				open := countingOpener(buildTar(false), true)
+				s := stream.FromTar(open.open, stream.TarOptions{BufferSize: 1 << 20})
+				entries := s.ToArray()
+				gomega.Expect(open.opened).To(gomega.Equal(1))
+				open.read = 0
+				gomega.Expect(entryContent(entries[2])).To(gomega.Equal("stream"))
+				gomega.Expect(open.opened).To(gomega.Equal(2))
+				gomega.Expect(open.read).To(gomega.Equal(len("stream")))
+				gomega.Expect(s.Map(entryContent).ToArray()).To(gomega.Equal([]interface{}{"hello", "world!", "stream"}))
+			})
+		})
+		ginkgo.When("Reading a bzip2 compressed tar archive", func() {
+			ginkgo.It("should decompress entries", func() {
+				data, _ := base64.StdEncoding.DecodeString(bzip2Tar)
+				arr := stream.FromTar(opener(data), stream.TarOptions{}).Map(entryContent).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{"hello"}))
+			})
+		})
+		ginkgo.When("Reading a truncated tar archive", func() {
+			ginkgo.It("should report an error", func() {
+				var errs []error
+				data := buildTar(false)
+				count := stream.FromTar(opener(data[:1124]), stream.TarOptions{
+					OnError: func(err error) {
+						errs = append(errs, err)
+					},
+				}).Count()
+				gomega.Expect(count).To(gomega.Equal(1))
+				gomega.Expect(errs).To(gomega.HaveLen(1))
+			})
+		})
+	})
+	ginkgo.Context("Zip source test", func() {
+		ginkgo.When("Reading a zip archive", func() {
+			ginkgo.It("should list and open entries", func() {
+				data := buildZip()
+				s := stream.FromZip(bytes.NewReader(data), int64(len(data)), nil)
+				gomega.Expect(s.Map(entryName).ToArray()).To(gomega.Equal([]interface{}{"a.txt", "dir/b.log", "c.txt"}))
+				gomega.Expect(s.AsParallel(2).FilterOrdered(isTxt).MapOrdered(entryContent).ToArray()).To(gomega.Equal([]interface{}{"hello", "stream"}))
+			})
+		})
+		ginkgo.When("Reading something other than a zip archive", func() {
+			ginkgo.It("should report an error", func() {
+				var errs []error
+				count := stream.FromZip(bytes.NewReader([]byte("nope")), 4, func(err error) {
+					errs = append(errs, err)
+				}).Count()
+				gomega.Expect(count).To(gomega.Equal(0))
+				gomega.Expect(errs).To(gomega.HaveLen(1))
+			})
+		})
+	})
+})
@@ -0,0 +1,79 @@
+package stream_test
+
+import (
+	"bytes"
+	"reflect"
+	"strconv"
+	"strings"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+type csvPerson struct {
+	Name string `csv:"name"`
+	Age  int    `csv:"age"`
+}
+
+var _ = ginkgo.Describe("Test CSV sources and sinks", func() {
+	ginkgo.Context("CSV source test", func() {
+		ginkgo.When("Reading CSV without header", func() {
+			ginkgo.It("should yield every record as a string array", func() {
+				arr := stream.FromCSV(strings.NewReader("a,1\nb,2\n"), stream.CSVOptions{}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{[]string{"a", "1"}, []string{"b", "2"}}))
+			})
+		})
+		ginkgo.When("Reading CSV with header", func() {
+			ginkgo.It("should yield every record as a header-keyed map", func() {
+				arr := stream.FromCSV(strings.NewReader("name;age\na;1\n"), stream.CSVOptions{
+					Comma:  ';',
+					Header: true,
+				}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{map[string]string{"name": "a", "age": "1"}}))
+			})
+			ginkgo.It("should decode records into structs", func() {
+				arr := stream.FromCSV(strings.NewReader("age,name\n1,a\n2,b\n"), stream.CSVOptions{
+					Type: reflect.TypeOf(csvPerson{}),
+				}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{csvPerson{Name: "a", Age: 1}, csvPerson{Name: "b", Age: 2}}))
+			})
+		})
+		ginkgo.When("Reading malformed CSV", func() {
+			ginkgo.It("should report bad rows and keep the others", func() {
+				var errs []error
+				arr := stream.FromCSV(strings.NewReader("name,age\na,1\nb\nc,x\nd,4\n"), stream.CSVOptions{
+					Type: reflect.TypeOf(csvPerson{}),
+					OnError: func(err error) {
+						errs = append(errs, err)
+					},
+				}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{csvPerson{Name: "a", Age: 1}, csvPerson{Name: "d", Age: 4}}))
+				gomega.Expect(errs).To(gomega.HaveLen(2))
+				gomega.Expect(errs[0].(*stream.RecordError).Index).To(gomega.Equal(2))
+				gomega.Expect(errs[1].(*stream.RecordError).Index).To(gomega.Equal(3))
+			})
+		})
+		ginkgo.When("Evaluating a CSV stream twice", func() {
+			ginkgo.It("should read the input only once", func() {
+				s := stream.FromCSV(strings.NewReader("a\nb\n"), stream.CSVOptions{})
+				gomega.Expect(s.Count()).To(gomega.Equal(2))
+				gomega.Expect(s.AsParallel(2).Count()).To(gomega.Equal(2))
+			})
+		})
+	})
+	ginkgo.Context("CSV sink test", func() {
+		ginkgo.When("Writing a stream as CSV", func() {
+			ginkgo.It("should write header and records", func() {
+				var buf bytes.Buffer
+				err := stream.OfParallel(2, 1, 2, 3).MapOrdered(func(item interface{}) interface{} {
+					return item.(int) * 10
+				}).ToCSV(&buf, []string{"value"}, func(item interface{}) []string {
+					return []string{strconv.Itoa(item.(int))}
+				})
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(buf.String()).To(gomega.Equal("value\n10\n20\n30\n"))
+			})
+		})
+	})
+})
@@ -0,0 +1,109 @@
+package stream_test
+
+import (
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing/fstest"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+func entryPath(item interface{}) interface{} {
+	return item.(stream.FileEntry).Path
+}
+
+var _ = ginkgo.Describe("Test file system sources", func() {
+	fsys := fstest.MapFS{
+		"a.go":         {Data: []byte("package a")},
+		"b.txt":        {Data: []byte("b")},
+		"dir/c.go":     {Data: []byte("package c")},
+		"dir/sub/d.go": {Data: []byte("package d")},
+	}
+	ginkgo.Context("File system walk test", func() {
+		ginkgo.When("Walking the whole tree", func() {
+			ginkgo.It("should yield every file in lexical order", func() {
+				arr := stream.FromFS(fsys, ".", stream.FSOptions{}).Map(entryPath).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{"a.go", "b.txt", "dir/c.go", "dir/sub/d.go"}))
+			})
+			ginkgo.It("should yield directories as well", func() {
+				arr := stream.FromFS(fsys, "dir", stream.FSOptions{Dirs: true}).Map(entryPath).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{"dir/c.go", "dir/sub", "dir/sub/d.go"}))
+			})
+		})
+		ginkgo.When("Walking with a pattern and a depth limit", func() {
+			ginkgo.It("should only yield matching files within depth", func() {
+				arr := stream.FromFS(fsys, ".", stream.FSOptions{
+					Pattern:  "*.go",
+					MaxDepth: 2,
+				}).Map(entryPath).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{"a.go", "dir/c.go"}))
+			})
+			ginkgo.It("should sum file sizes", func() {
+				size := stream.FromFS(fsys, ".", stream.FSOptions{Pattern: "*.go"}).Reduce(int64(0), func(acc, cur interface{}) interface{} {
+					info, _ := cur.(stream.FileEntry).Info()
+					return acc.(int64) + info.Size()
+				})
+				gomega.Expect(size).To(gomega.Equal(int64(27)))
+			})
+		})
+		ginkgo.When("Walking a missing directory", func() {
+			ginkgo.It("should report an error", func() {
+				var errs []error
+				count := stream.FromFS(fsys, "missing", stream.FSOptions{
+					OnError: func(err error) {
+						errs = append(errs, err)
+					},
+				}).Count()
+				gomega.Expect(count).To(gomega.Equal(0))
+				gomega.Expect(errs).To(gomega.HaveLen(1))
+			})
+		})
+		ginkgo.When("Walking a tree with symbolic links", func() {
+			var dir string
+			ginkgo.BeforeEach(func() {
+				var err error
+				dir, err = os.MkdirTemp("", "stream-fs")
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(os.MkdirAll(filepath.Join(dir, "real"), 0755)).To(gomega.Succeed())
+				gomega.Expect(os.WriteFile(filepath.Join(dir, "real", "f.txt"), []byte("f"), 0644)).To(gomega.Succeed())
+				gomega.Expect(os.Symlink("real", filepath.Join(dir, "link"))).To(gomega.Succeed())
+			})
+			ginkgo.AfterEach(func() {
+				os.RemoveAll(dir)
+			})
+			ginkgo.It("should keep links by default", func() {
+				arr := stream.FromFS(os.DirFS(dir), ".", stream.FSOptions{}).Map(entryPath).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{"link", "real/f.txt"}))
+			})
+			ginkgo.It("should skip links", func() {
+				arr := stream.FromFS(os.DirFS(dir), ".", stream.FSOptions{Symlinks: stream.SymlinkSkip}).Map(entryPath).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{"real/f.txt"}))
+			})
+			ginkgo.It("should follow links", func() {
+				s := stream.FromFS(os.DirFS(dir), ".", stream.FSOptions{Symlinks: stream.SymlinkFollow})
+				gomega.Expect(s.Map(entryPath).ToArray()).To(gomega.Equal([]interface{}{"link/f.txt", "real/f.txt"}))
+				gomega.Expect(s.AllMatch(func(item interface{}) bool {
+					return item.(stream.FileEntry).Type()&fs.ModeSymlink == 0
+				})).To(gomega.BeTrue())
+			})
+			ginkgo.It("should report links forming a cycle instead of following them", func() {
+				gomega.Expect(os.Symlink("..", filepath.Join(dir, "real", "up"))).To(gomega.Succeed())
+				errs := make([]error, 0)
+				arr := stream.FromFS(os.DirFS(dir), ".", stream.FSOptions{
+					Symlinks: stream.SymlinkFollow,
+					Dirs:     true,
+					OnError: func(err error) {
+						errs = append(errs, err)
+					},
+				}).Map(entryPath).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{"link", "link/f.txt", "link/up", "real", "real/f.txt", "real/up"}))
+				gomega.Expect(errs).To(gomega.HaveLen(2))
+				gomega.Expect(errs[0]).To(gomega.MatchError("symbolic link link/up forms a cycle"))
+				gomega.Expect(errs[1]).To(gomega.MatchError("symbolic link real/up forms a cycle"))
+			})
+		})
+	})
+})
@@ -0,0 +1,87 @@
+package stream_test
+
+import (
+	"bytes"
+	"strings"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+type jsonEvent struct {
+	ID   int    `json:"id"`
+	Kind string `json:"kind"`
+}
+
+func newJSONEvent() interface{} {
+	return &jsonEvent{}
+}
+
+var _ = ginkgo.Describe("Test JSON sources and sinks", func() {
+	ginkgo.Context("JSON Lines source test", func() {
+		ginkgo.When("Reading JSON Lines", func() {
+			ginkgo.It("should decode every line", func() {
+				arr := stream.FromJSONLines(strings.NewReader("{\"id\":1,\"kind\":\"a\"}\n\n{\"id\":2,\"kind\":\"b\"}"), stream.JSONOptions{NewValue: newJSONEvent}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{&jsonEvent{ID: 1, Kind: "a"}, &jsonEvent{ID: 2, Kind: "b"}}))
+			})
+			ginkgo.It("should decode into generic values", func() {
+				arr := stream.FromJSONLines(strings.NewReader("1\n\"a\"\n"), stream.JSONOptions{}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{float64(1), "a"}))
+			})
+		})
+		ginkgo.When("Reading malformed JSON Lines", func() {
+			ginkgo.It("should report bad lines and keep the others", func() {
+				var errs []error
+				arr := stream.FromJSONLines(strings.NewReader("{\"id\":1}\n{\"id\":\n{\"id\":\"x\"}\n{\"id\":4}\n"), stream.JSONOptions{
+					NewValue: newJSONEvent,
+					OnError: func(err error) {
+						errs = append(errs, err)
+					},
+				}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{&jsonEvent{ID: 1}, &jsonEvent{ID: 4}}))
+				gomega.Expect(errs).To(gomega.HaveLen(2))
+				gomega.Expect(errs[0].(*stream.RecordError).Index).To(gomega.Equal(2))
+				gomega.Expect(errs[1].(*stream.RecordError).Index).To(gomega.Equal(3))
+			})
+		})
+	})
+	ginkgo.Context("JSON array source test", func() {
+		ginkgo.When("Reading a JSON array", func() {
+			ginkgo.It("should decode every element", func() {
+				var errs []error
+				arr := stream.FromJSONArray(strings.NewReader("[{\"id\":1}, {\"id\":\"x\"}, {\"id\":3}]"), stream.JSONOptions{
+					NewValue: newJSONEvent,
+					OnError: func(err error) {
+						errs = append(errs, err)
+					},
+				}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{&jsonEvent{ID: 1}, &jsonEvent{ID: 3}}))
+				gomega.Expect(errs).To(gomega.HaveLen(1))
+				gomega.Expect(errs[0].(*stream.RecordError).Index).To(gomega.Equal(2))
+			})
+		})
+		ginkgo.When("Reading something other than an array", func() {
+			ginkgo.It("should report an error", func() {
+				var errs []error
+				count := stream.FromJSONArray(strings.NewReader("{\"id\":1}"), stream.JSONOptions{
+					OnError: func(err error) {
+						errs = append(errs, err)
+					},
+				}).Count()
+				gomega.Expect(count).To(gomega.Equal(0))
+				gomega.Expect(errs).To(gomega.HaveLen(1))
+			})
+		})
+	})
+	ginkgo.Context("JSON Lines sink test", func() {
+		ginkgo.When("Writing a stream as JSON Lines", func() {
+			ginkgo.It("should write a value per line", func() {
+				var buf bytes.Buffer
+				err := stream.Of(jsonEvent{ID: 1, Kind: "a"}, 2).ToJSONLines(&buf)
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(buf.String()).To(gomega.Equal("{\"id\":1,\"kind\":\"a\"}\n2\n"))
+			})
+		})
+	})
+})
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"reflect"
 	"strconv"
+	"sync/atomic"
 
 	"github.com/Workiva/go-datastructures/list"
 	"github.com/dynastywind/go-stream/stream"
@@ -99,6 +100,27 @@ var _ = ginkgo.Describe("Test if every function works well", func() {
 				}).ToArray()
 				gomega.Expect(arr).To(BagEquals([]interface{}{1, 2}))
 			})
+			ginkgo.It("should keep first occurrences in encounter order", func() {
+				type pair struct {
+					key   int
+					index int
+				}
+				arr := stream.OfParallel(3, pair{3, 0}, pair{1, 1}, pair{3, 2}, pair{2, 3}, pair{1, 4}).Distinct(func(item interface{}) string {
+					return strconv.Itoa(item.(pair).key)
+				}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{pair{3, 0}, pair{1, 1}, pair{2, 3}}))
+			})
+		})
+		ginkgo.When("Executing DistinctBy", func() {
+			ginkgo.It("should return unique values by a comparable key", func() {
+				type point struct {
+					x, y int
+				}
+				arr := stream.OfParallel(2, []int{1, 2}, []int{3, 4}, []int{1, 2}, []int{5, 6}).DistinctBy(func(item interface{}) interface{} {
+					return point{item.([]int)[0], item.([]int)[1]}
+				}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{[]int{1, 2}, []int{3, 4}, []int{5, 6}}))
+			})
 		})
 		ginkgo.When("Executing Filter", func() {
 			ginkgo.It("should return an array of values greater than 2", func() {
@@ -115,6 +137,18 @@ var _ = ginkgo.Describe("Test if every function works well", func() {
 				}).ToTypedArray(reflect.TypeOf(1)).Interface().([]int)
 				gomega.Expect(arr).To(gomega.Equal([]int{3, 4}))
 			})
+			ginkgo.It("should keep unhashable and repeated items in order", func() {
+				items := make([]interface{}, 50)
+				for i := range items {
+					items[i] = []int{i % 5, i}
+				}
+				arr := stream.OfParallel(4, items...).FilterOrdered(func(item interface{}) bool {
+					return item.([]int)[0] != 0
+				}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal(stream.Of(items...).Filter(func(item interface{}) bool {
+					return item.([]int)[0] != 0
+				}).ToArray()))
+			})
 		})
 		ginkgo.When("Executing FindAny", func() {
 			ginkgo.It("should find a value", func() {
@@ -243,6 +277,46 @@ var _ = ginkgo.Describe("Test if every function works well", func() {
 				gomega.Expect(min.IsPresent()).To(gomega.BeFalse())
 			})
 		})
+		ginkgo.When("Executing MaxBy", func() {
+			ginkgo.It("should return the value with the maximum key", func() {
+				max := stream.OfParallel(2, "bb", "a", "ccc", "dd").MaxBy(func(item interface{}) interface{} {
+					return len(item.(string))
+				})
+				gomega.Expect(max).To(gomega.Equal(util.Of("ccc")))
+			})
+			ginkgo.It("should return the first of equal values", func() {
+				max := stream.OfParallel(2, "bb", "a", "dd").MaxBy(func(item interface{}) interface{} {
+					return len(item.(string))
+				})
+				gomega.Expect(max).To(gomega.Equal(util.Of("bb")))
+			})
+		})
+		ginkgo.When("Executing MinBy", func() {
+			ginkgo.It("should return the value with the minimal key", func() {
+				min := stream.OfParallel(2, "bb", "a", "ccc", "d").MinBy(func(item interface{}) interface{} {
+					return len(item.(string))
+				})
+				gomega.Expect(min).To(gomega.Equal(util.Of("a")))
+			})
+		})
+		ginkgo.When("Executing MinMax", func() {
+			ginkgo.It("should return both extremes without reordering data", func() {
+				arr := []interface{}{3, 5, 1, 4, 2}
+				min, max := stream.FromArrayParallel(2, arr).MinMax(func(a, b interface{}) bool {
+					return a.(int) < b.(int)
+				})
+				gomega.Expect(min).To(gomega.Equal(util.Of(1)))
+				gomega.Expect(max).To(gomega.Equal(util.Of(5)))
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{3, 5, 1, 4, 2}))
+			})
+			ginkgo.It("should return empty", func() {
+				min, max := stream.OfParallel(2).MinMax(func(a, b interface{}) bool {
+					return a.(int) < b.(int)
+				})
+				gomega.Expect(min.IsPresent()).To(gomega.BeFalse())
+				gomega.Expect(max.IsPresent()).To(gomega.BeFalse())
+			})
+		})
 		ginkgo.When("Executing NoneMatch", func() {
 			ginkgo.It("should return true", func() {
 				result := stream.OfParallel(2, 1, 2, 3, 4).NoneMatch(func(item interface{}) bool {
@@ -278,11 +352,23 @@ var _ = ginkgo.Describe("Test if every function works well", func() {
 				})
 				gomega.Expect(result).To(gomega.Equal(10))
 			})
+			ginkgo.It("should never merge two accumulated values with a reducer changing type", func() {
+				result := stream.OfParallel(2, 1, 2, 3, 4).Reduce(int64(0), func(acc, cur interface{}) interface{} {
+					return acc.(int64) + int64(cur.(int))
+				})
+				gomega.Expect(result).To(gomega.Equal(int64(10)))
+			})
+			ginkgo.It("should use an initial value which is not an identity once", func() {
+				sum := func(acc, cur interface{}) interface{} {
+					return acc.(int) + cur.(int)
+				}
+				gomega.Expect(stream.OfParallel(4, 1, 2, 3, 4).Reduce(10, sum)).To(gomega.Equal(20))
+			})
 		})
 		ginkgo.When("Executing ReduceCombine", func() {
 			ginkgo.It("should reduce to 10 of int64 type", func() {
 				result := stream.OfParallel(2, 1, 2, 3, 4).ReduceCombine(int64(0), func(acc, cur interface{}) interface{} {
-					return int64(cur.(int))
+					return acc.(int64) + int64(cur.(int))
 				}, func(acc, cur interface{}) interface{} {
 					return acc.(int64) + cur.(int64)
 				})
@@ -290,7 +376,7 @@ var _ = ginkgo.Describe("Test if every function works well", func() {
 			})
 			ginkgo.It("should reduce to initial value of int64 type", func() {
 				result := stream.OfParallel(1).ReduceCombine(int64(0), func(acc, cur interface{}) interface{} {
-					return int64(cur.(int))
+					return acc.(int64) + int64(cur.(int))
 				}, func(acc, cur interface{}) interface{} {
 					return acc.(int64) + cur.(int64)
 				})
@@ -310,6 +396,62 @@ var _ = ginkgo.Describe("Test if every function works well", func() {
 				})
 				gomega.Expect(result.IsPresent()).To(gomega.BeFalse())
 			})
+			ginkgo.It("should reduce chunks in parallel", func() {
+				var calls int32
+				result := stream.OfParallel(4, 1, 2, 3, 4, 5, 6, 7, 8).ReduceOptional(func(acc, cur interface{}) interface{} {
+					atomic.AddInt32(&calls, 1)
+					return acc.(int) + cur.(int)
+				})
+				gomega.Expect(result.Get()).To(gomega.Equal(36))
+				// Each of the 4 chunks of 2 items needs one call, and merging 4 partial results needs 3 more
+				gomega.Expect(calls).To(gomega.Equal(int32(7)))
+			})
+			ginkgo.It("should reduce fewer items than go routines", func() {
+				result := stream.OfParallel(8, 1, 2, 3).ReduceOptional(func(acc, cur interface{}) interface{} {
+					return acc.(int) + cur.(int)
+				})
+				gomega.Expect(result.Get()).To(gomega.Equal(6))
+			})
+		})
+		ginkgo.When("Executing ReduceCombine with several chunks", func() {
+			ginkgo.It("should only combine partial results of chunks", func() {
+				var calls int32
+				result := stream.OfParallel(4, 1, 2, 3, 4, 5, 6, 7, 8).ReduceCombine(int64(0), func(acc, cur interface{}) interface{} {
+					return acc.(int64) + int64(cur.(int))
+				}, func(acc, cur interface{}) interface{} {
+					atomic.AddInt32(&calls, 1)
+					return acc.(int64) + cur.(int64)
+				})
+				gomega.Expect(result).To(gomega.Equal(int64(36)))
+				gomega.Expect(calls).To(gomega.Equal(int32(3)))
+			})
+			ginkgo.It("should keep chunk order in ordered mode", func() {
+				result := stream.OfParallel(3, "a", "b", "c", "d", "e", "f", "g").ReduceCombineOrdered("", func(acc, cur interface{}) interface{} {
+					return acc.(string) + cur.(string)
+				}, func(acc, cur interface{}) interface{} {
+					return acc.(string) + cur.(string)
+				})
+				gomega.Expect(result).To(gomega.Equal("abcdefg"))
+			})
+		})
+		ginkgo.When("Executing ReduceOptionalOrdered", func() {
+			ginkgo.It("should reduce a non-commutative function in order", func() {
+				items := make([]interface{}, 100)
+				for i := range items {
+					items[i] = strconv.Itoa(i % 10)
+				}
+				concat := func(acc, cur interface{}) interface{} {
+					return acc.(string) + cur.(string)
+				}
+				expected := stream.Of(items...).Reduce("", concat)
+				gomega.Expect(stream.OfParallel(7, items...).ReduceOptionalOrdered(concat).Get()).To(gomega.Equal(expected))
+			})
+			ginkgo.It("should reduce to nothing", func() {
+				result := stream.OfParallel(3).ReduceOptionalOrdered(func(acc, cur interface{}) interface{} {
+					return cur.(int)
+				})
+				gomega.Expect(result.IsPresent()).To(gomega.BeFalse())
+			})
 		})
 		ginkgo.When("Executing Reverse", func() {
 			ginkgo.It("should reverse the original array", func() {
@@ -97,6 +97,14 @@ var _ = ginkgo.Describe("Test if every function works well", func() {
 				gomega.Expect(arr).To(gomega.Equal([]int{1, 2}))
 			})
 		})
+		ginkgo.When("Executing DistinctBy", func() {
+			ginkgo.It("should return unique values by a comparable key", func() {
+				arr := stream.Of("apple", "avocado", "banana", "blueberry", "cherry").DistinctBy(func(item interface{}) interface{} {
+					return item.(string)[0]
+				}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{"apple", "banana", "cherry"}))
+			})
+		})
 		ginkgo.When("Executing Filter", func() {
 			ginkgo.It("should return an array of values greater than 2", func() {
 				arr := stream.Of(1, 2, 3, 4).Filter(func(item interface{}) bool {
@@ -240,6 +248,46 @@ var _ = ginkgo.Describe("Test if every function works well", func() {
 				gomega.Expect(min.IsPresent()).To(gomega.BeFalse())
 			})
 		})
+		ginkgo.When("Executing MaxBy", func() {
+			ginkgo.It("should return the value with the maximum key", func() {
+				max := stream.Of("bb", "a", "ccc", "dd").MaxBy(func(item interface{}) interface{} {
+					return len(item.(string))
+				})
+				gomega.Expect(max).To(gomega.Equal(util.Of("ccc")))
+			})
+			ginkgo.It("should return the first of equal values", func() {
+				max := stream.Of("bb", "a", "dd").MaxBy(func(item interface{}) interface{} {
+					return len(item.(string))
+				})
+				gomega.Expect(max).To(gomega.Equal(util.Of("bb")))
+			})
+		})
+		ginkgo.When("Executing MinBy", func() {
+			ginkgo.It("should return the value with the minimal key", func() {
+				min := stream.Of("bb", "a", "ccc", "d").MinBy(func(item interface{}) interface{} {
+					return len(item.(string))
+				})
+				gomega.Expect(min).To(gomega.Equal(util.Of("a")))
+			})
+		})
+		ginkgo.When("Executing MinMax", func() {
+			ginkgo.It("should return both extremes without reordering data", func() {
+				arr := []interface{}{3, 5, 1, 4, 2}
+				min, max := stream.FromArray(arr).MinMax(func(a, b interface{}) bool {
+					return a.(int) < b.(int)
+				})
+				gomega.Expect(min).To(gomega.Equal(util.Of(1)))
+				gomega.Expect(max).To(gomega.Equal(util.Of(5)))
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{3, 5, 1, 4, 2}))
+			})
+			ginkgo.It("should return empty", func() {
+				min, max := stream.Of().MinMax(func(a, b interface{}) bool {
+					return a.(int) < b.(int)
+				})
+				gomega.Expect(min.IsPresent()).To(gomega.BeFalse())
+				gomega.Expect(max.IsPresent()).To(gomega.BeFalse())
+			})
+		})
 		ginkgo.When("Executing NoneMatch", func() {
 			ginkgo.It("should return true", func() {
 				result := stream.Of(1, 2, 3, 4).NoneMatch(func(item interface{}) bool {
@@ -279,7 +327,7 @@ var _ = ginkgo.Describe("Test if every function works well", func() {
 		ginkgo.When("Executing ReduceCombine", func() {
 			ginkgo.It("should reduce to 10 of int64 type", func() {
 				result := stream.Of(1, 2, 3, 4).ReduceCombine(int64(0), func(acc, cur interface{}) interface{} {
-					return int64(cur.(int))
+					return acc.(int64) + int64(cur.(int))
 				}, func(acc, cur interface{}) interface{} {
 					return acc.(int64) + cur.(int64)
 				})
@@ -287,7 +335,7 @@ var _ = ginkgo.Describe("Test if every function works well", func() {
 			})
 			ginkgo.It("should reduce to initial value of int64 type", func() {
 				result := stream.Of().ReduceCombine(int64(0), func(acc, cur interface{}) interface{} {
-					return int64(cur.(int))
+					return acc.(int64) + int64(cur.(int))
 				}, func(acc, cur interface{}) interface{} {
 					return acc.(int64) + cur.(int64)
 				})
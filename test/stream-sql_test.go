@@ -0,0 +1,230 @@
+package stream_test
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+// fakeDB is an in-memory database behind the "stream-fake" driver
+// Queries return the rows of the table, and executed statements append their arguments to it
+type fakeDB struct {
+	mu         sync.Mutex
+	table      [][]driver.Value
+	rowsClosed bool
+	commits    int
+	rollbacks  int
+}
+
+var fakeDatabase = &fakeDB{}
+
+func init() {
+	sql.Register("stream-fake", fakeDriver{})
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(name string) (driver.Conn, error) {
+	return &fakeConn{}, nil
+}
+
+type fakeConn struct {
+	pending [][]driver.Value
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	return &fakeStmt{conn: c}, nil
+}
+
+func (c *fakeConn) Close() error {
+	return nil
+}
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	c.pending = nil
+	return &fakeTx{conn: c}, nil
+}
+
+type fakeTx struct {
+	conn *fakeConn
+}
+
+func (tx *fakeTx) Commit() error {
+	fakeDatabase.mu.Lock()
+	defer fakeDatabase.mu.Unlock()
+	fakeDatabase.table = append(fakeDatabase.table, tx.conn.pending...)
+	fakeDatabase.commits++
+	return nil
+}
+
+func (tx *fakeTx) Rollback() error {
+	fakeDatabase.mu.Lock()
+	defer fakeDatabase.mu.Unlock()
+	fakeDatabase.rollbacks++
+	return nil
+}
+
+type fakeStmt struct {
+	conn *fakeConn
+}
+
+func (s *fakeStmt) Close() error {
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int {
+	return -1
+}
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	for _, arg := range args {
+		if arg == "fail" {
+			return nil, errors.New("exec failed")
+		}
+	}
+	s.conn.pending = append(s.conn.pending, args)
+	return driver.RowsAffected(1), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	fakeDatabase.mu.Lock()
+	defer fakeDatabase.mu.Unlock()
+	fakeDatabase.rowsClosed = false
+	return &fakeRows{rows: fakeDatabase.table}, nil
+}
+
+type fakeRows struct {
+	rows  [][]driver.Value
+	index int
+}
+
+func (r *fakeRows) Columns() []string {
+	return []string{"id", "name"}
+}
+
+func (r *fakeRows) Close() error {
+	fakeDatabase.mu.Lock()
+	defer fakeDatabase.mu.Unlock()
+	fakeDatabase.rowsClosed = true
+	return nil
+}
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.index >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.index])
+	r.index++
+	return nil
+}
+
+type sqlUser struct {
+	ID   int64
+	Name string
+}
+
+func scanUser(rows *sql.Rows) (interface{}, error) {
+	var user sqlUser
+	err := rows.Scan(&user.ID, &user.Name)
+	return user, err
+}
+
+var _ = ginkgo.Describe("Test database sources and sinks", func() {
+	var db *sql.DB
+	ginkgo.BeforeEach(func() {
+		fakeDatabase = &fakeDB{}
+		var err error
+		db, err = sql.Open("stream-fake", "")
+		gomega.Expect(err).To(gomega.BeNil())
+	})
+	ginkgo.AfterEach(func() {
+		db.Close()
+	})
+	ginkgo.Context("Rows source test", func() {
+		ginkgo.When("Reading rows", func() {
+			ginkgo.It("should scan every row and close rows", func() {
+				fakeDatabase.table = [][]driver.Value{{int64(1), "a"}, {int64(2), "b"}}
+				rows, err := db.Query("SELECT id, name FROM users")
+				gomega.Expect(err).To(gomega.BeNil())
+				s := stream.FromRows(context.Background(), rows, scanUser, nil)
+				gomega.Expect(fakeDatabase.rowsClosed).To(gomega.BeFalse())
+				gomega.Expect(s.ToArray()).To(gomega.Equal([]interface{}{sqlUser{1, "a"}, sqlUser{2, "b"}}))
+				gomega.Expect(fakeDatabase.rowsClosed).To(gomega.BeTrue())
+			})
+			ginkgo.It("should close rows of a stream which is never evaluated once the context is canceled", func() {
+				fakeDatabase.table = [][]driver.Value{{int64(1), "a"}}
+				rows, err := db.Query("SELECT id, name FROM users")
+				gomega.Expect(err).To(gomega.BeNil())
+				ctx, cancel := context.WithCancel(context.Background())
+				stream.FromRows(ctx, rows, scanUser, nil)
+				gomega.Expect(fakeDatabase.rowsClosed).To(gomega.BeFalse())
+				cancel()
+				gomega.Eventually(func() bool {
+					fakeDatabase.mu.Lock()
+					defer fakeDatabase.mu.Unlock()
+					return fakeDatabase.rowsClosed
+				}).Should(gomega.BeTrue())
+			})
+			ginkgo.It("should report the context error of a stream evaluated after cancellation", func() {
+				fakeDatabase.table = [][]driver.Value{{int64(1), "a"}}
+				rows, err := db.Query("SELECT id, name FROM users")
+				gomega.Expect(err).To(gomega.BeNil())
+				ctx, cancel := context.WithCancel(context.Background())
+				cancel()
+				var errs []error
+				arr := stream.FromRows(ctx, rows, scanUser, func(err error) {
+					errs = append(errs, err)
+				}).ToArray()
+				gomega.Expect(arr).To(gomega.BeEmpty())
+				gomega.Expect(errs).To(gomega.Equal([]error{context.Canceled}))
+			})
+			ginkgo.It("should report rows failing to be scanned", func() {
+				fakeDatabase.table = [][]driver.Value{{int64(1), "a"}, {"x", "b"}, {int64(3), "c"}}
+				rows, err := db.Query("SELECT id, name FROM users")
+				gomega.Expect(err).To(gomega.BeNil())
+				var errs []error
+				arr := stream.FromRows(context.Background(), rows, scanUser, func(err error) {
+					errs = append(errs, err)
+				}).ToArray()
+				gomega.Expect(arr).To(gomega.Equal([]interface{}{sqlUser{1, "a"}, sqlUser{3, "c"}}))
+				gomega.Expect(errs).To(gomega.HaveLen(1))
+				gomega.Expect(errs[0].(*stream.RecordError).Index).To(gomega.Equal(2))
+			})
+		})
+	})
+	ginkgo.Context("Batched insert sink test", func() {
+		ginkgo.When("Writing in batches", func() {
+			ginkgo.It("should commit a transaction per batch", func() {
+				err := stream.OfParallel(2, "a", "b", "c", "d", "e").ToSQLBatches(db, "INSERT INTO users (name) VALUES (?)", 2, func(item interface{}) []interface{} {
+					return []interface{}{item}
+				})
+				gomega.Expect(err).To(gomega.BeNil())
+				gomega.Expect(fakeDatabase.table).To(gomega.HaveLen(5))
+				gomega.Expect(fakeDatabase.commits).To(gomega.Equal(3))
+			})
+			ginkgo.It("should roll back the failing batch", func() {
+				err := stream.Of("a", "b", "fail", "d").ToSQLBatches(db, "INSERT INTO users (name) VALUES (?)", 2, func(item interface{}) []interface{} {
+					return []interface{}{item}
+				})
+				gomega.Expect(err).NotTo(gomega.BeNil())
+				gomega.Expect(fakeDatabase.table).To(gomega.Equal([][]driver.Value{{"a"}, {"b"}}))
+				gomega.Expect(fakeDatabase.commits).To(gomega.Equal(1))
+				gomega.Expect(fakeDatabase.rollbacks).To(gomega.Equal(1))
+			})
+			ginkgo.It("should reject an invalid batch size", func() {
+				err := stream.Of("a").ToSQLBatches(db, "INSERT INTO users (name) VALUES (?)", 0, func(item interface{}) []interface{} {
+					return []interface{}{item}
+				})
+				gomega.Expect(err).NotTo(gomega.BeNil())
+				gomega.Expect(fakeDatabase.table).To(gomega.BeEmpty())
+			})
+		})
+	})
+})
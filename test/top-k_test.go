@@ -0,0 +1,60 @@
+package stream_test
+
+import (
+	"math"
+	"math/rand"
+	"sort"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var _ = ginkgo.Describe("Test top-K operations", func() {
+	original := sortItems(rand.New(rand.NewSource(3)), 3000, 50, false)
+	ascending := make([]interface{}, len(original))
+	copy(ascending, original)
+	sort.SliceStable(ascending, func(i, j int) bool {
+		return lessByKey(ascending[i], ascending[j])
+	})
+	// Largest first, equal items in their original order
+	descending := make([]interface{}, len(original))
+	copy(descending, original)
+	sort.SliceStable(descending, func(i, j int) bool {
+		return lessByKey(descending[j], descending[i])
+	})
+
+	ginkgo.When("Executing TopK", func() {
+		ginkgo.It("should return the largest items in a sequential stream", func() {
+			arr := stream.FromArray(original).TopK(10, lessByKey).ToArray()
+			gomega.Expect(arr).To(gomega.Equal(descending[:10]))
+		})
+		ginkgo.It("should return the largest items in a parallel stream", func() {
+			arr := stream.FromArrayParallel(4, original).TopK(100, lessByKey).ToArray()
+			gomega.Expect(arr).To(gomega.Equal(descending[:100]))
+		})
+		ginkgo.It("should return every item when k exceeds the stream length", func() {
+			arr := stream.Of(1, 3, 2).TopK(5, lessInt).ToArray()
+			gomega.Expect(arr).To(gomega.Equal([]interface{}{3, 2, 1}))
+		})
+		ginkgo.It("should not allocate for k when k is much larger than the stream", func() {
+			gomega.Expect(stream.Of(1, 3, 2).TopK(math.MaxInt64, lessInt).ToArray()).To(gomega.Equal([]interface{}{3, 2, 1}))
+			gomega.Expect(stream.OfParallel(2, 1, 3, 2).BottomK(math.MaxInt64, lessInt).ToArray()).To(gomega.Equal([]interface{}{1, 2, 3}))
+			gomega.Expect(stream.Of(1, 3, 2).Sorted(lessInt).Limit(math.MaxInt64).ToArray()).To(gomega.Equal([]interface{}{1, 2, 3}))
+		})
+		ginkgo.It("should return nothing when k is not positive", func() {
+			arr := stream.OfParallel(2, 1, 3, 2).TopK(0, lessInt).ToArray()
+			gomega.Expect(arr).To(gomega.BeEmpty())
+		})
+	})
+	ginkgo.When("Executing BottomK", func() {
+		ginkgo.It("should return the smallest items in a sequential stream", func() {
+			arr := stream.FromArray(original).BottomK(10, lessByKey).ToArray()
+			gomega.Expect(arr).To(gomega.Equal(ascending[:10]))
+		})
+		ginkgo.It("should return the smallest items after converting to a parallel stream", func() {
+			arr := stream.FromArray(original).BottomK(100, lessByKey).AsParallel(3).ToArray()
+			gomega.Expect(arr).To(gomega.Equal(ascending[:100]))
+		})
+	})
+})
@@ -0,0 +1,157 @@
+package stream_test
+
+import (
+	"io"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/dynastywind/go-stream/stream"
+	"github.com/onsi/ginkgo"
+	"github.com/onsi/gomega"
+)
+
+var pipelines = map[stream.OperationTag]func(stream.Stream) stream.Stream{
+	stream.BOTTOM_K: func(s stream.Stream) stream.Stream {
+		return s.BottomK(5, lessInt)
+	},
+	stream.DISTINCT: func(s stream.Stream) stream.Stream {
+		return s.Distinct(itoa)
+	},
+	stream.DISTINCT_APPROX: func(s stream.Stream) stream.Stream {
+		return s.DistinctApprox(itoa, 100, 0.001)
+	},
+	stream.DISTINCT_BY: func(s stream.Stream) stream.Stream {
+		return s.DistinctBy(func(item interface{}) interface{} {
+			return item.(int) % 7
+		})
+	},
+	stream.DISTINCT_WITHIN: func(s stream.Stream) stream.Stream {
+		return s.DistinctWithin(itoa, 3)
+	},
+	stream.DISTINCT_WITHIN_TIME: func(s stream.Stream) stream.Stream {
+		return s.DistinctWithinTime(itoa, func(item interface{}) time.Time {
+			return time.Unix(int64(item.(int)), 0)
+		}, 5*time.Second)
+	},
+	stream.FILTER: func(s stream.Stream) stream.Stream {
+		return s.Filter(isEven)
+	},
+	stream.FILTER_ORDERED: func(s stream.Stream) stream.Stream {
+		return s.FilterOrdered(isEven)
+	},
+	stream.FLAT_MAP: func(s stream.Stream) stream.Stream {
+		return s.FlatMap(func(item interface{}) []interface{} {
+			return []interface{}{item, item}
+		})
+	},
+	stream.FLAT_MAP_ORDERED: func(s stream.Stream) stream.Stream {
+		return s.FlatMapOrdered(func(item interface{}) []interface{} {
+			return []interface{}{item, item}
+		})
+	},
+	stream.LIMIT: func(s stream.Stream) stream.Stream {
+		return s.Limit(10)
+	},
+	stream.MAP: func(s stream.Stream) stream.Stream {
+		return s.Map(double)
+	},
+	stream.MAP_ORDERED: func(s stream.Stream) stream.Stream {
+		return s.MapOrdered(double)
+	},
+	stream.PEEK: func(s stream.Stream) stream.Stream {
+		return s.Peek(func(item interface{}) {})
+	},
+	stream.REVERSE: func(s stream.Stream) stream.Stream {
+		return s.Reverse()
+	},
+	stream.SAMPLE: func(s stream.Stream) stream.Stream {
+		return s.WithRandom(seeded()).Sample(5)
+	},
+	stream.SAMPLE_FRACTION: func(s stream.Stream) stream.Stream {
+		return s.WithRandom(seeded()).SampleFraction(0.3)
+	},
+	stream.SHUFFLE: func(s stream.Stream) stream.Stream {
+		return s.WithRandom(seeded()).Shuffle()
+	},
+	stream.SKIP: func(s stream.Stream) stream.Stream {
+		return s.Skip(10)
+	},
+	stream.SORTED: func(s stream.Stream) stream.Stream {
+		return s.Sorted(lessInt)
+	},
+	stream.TOP_K: func(s stream.Stream) stream.Stream {
+		return s.TopK(5, lessInt)
+	},
+}
+
+type countingReader struct {
+	io.Reader
+	reads int
+}
+
+func (r *countingReader) Read(p []byte) (int, error) {
+	r.reads++
+	return r.Reader.Read(p)
+}
+
+func sortedInts(arr []interface{}) []interface{} {
+	result := append([]interface{}{}, arr...)
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].(int) < result[j].(int)
+	})
+	return result
+}
+
+var _ = ginkgo.Describe("Test conversion between sequential and parallel streams", func() {
+	items := make([]interface{}, 200)
+	for i := range items {
+		items[i] = i * 7 % 50
+	}
+	conversions := map[string]func(func(stream.Stream) stream.Stream) stream.Stream{
+		"sequential to parallel": func(build func(stream.Stream) stream.Stream) stream.Stream {
+			return build(stream.Of(items...)).AsParallel(3)
+		},
+		"parallel to sequential": func(build func(stream.Stream) stream.Stream) stream.Stream {
+			return build(stream.OfParallel(2, items...)).AsSequence()
+		},
+		"parallel to parallel with more routines": func(build func(stream.Stream) stream.Stream) stream.Stream {
+			return build(stream.OfParallel(2, items...)).AsParallel(5)
+		},
+		"back and forth": func(build func(stream.Stream) stream.Stream) stream.Stream {
+			return build(stream.Of(items...)).AsParallel(4).AsSequence()
+		},
+	}
+	ginkgo.It("should carry over every operation", func() {
+		for _, tag := range stream.Tags() {
+			build, ok := pipelines[tag]
+			gomega.Expect(ok).To(gomega.BeTrue(), "no pipeline to test %v", tag)
+			expected := build(stream.Of(items...))
+			for name, convert := range conversions {
+				converted := convert(build)
+				gomega.Expect(stageTags(converted.Unoptimized())).To(gomega.Equal(stageTags(expected.Unoptimized())), "%v from %s", tag, name)
+				ordered := true
+				for _, stage := range converted.Explain().Stages {
+					ordered = ordered && stage.Ordered
+				}
+				if ordered {
+					gomega.Expect(converted.ToArray()).To(gomega.Equal(build(stream.Of(items...)).ToArray()), "%v from %s", tag, name)
+				} else {
+					gomega.Expect(sortedInts(converted.ToArray())).To(gomega.Equal(sortedInts(build(stream.Of(items...)).ToArray())), "%v from %s", tag, name)
+				}
+			}
+		}
+	})
+	ginkgo.It("should use the new number of go routines", func() {
+		s := stream.OfParallel(2, items...).MapOrdered(double).AsParallel(6)
+		gomega.Expect(s.Explain().Routines).To(gomega.Equal(6))
+		gomega.Expect(s.AsParallel(6)).To(gomega.BeIdenticalTo(s))
+	})
+	ginkgo.It("should read the source when evaluated only", func() {
+		r := &countingReader{Reader: strings.NewReader("1\n2\n")}
+		s := stream.FromJSONLines(r, stream.JSONOptions{}).AsParallel(2).AsSequence()
+		gomega.Expect(r.reads).To(gomega.Equal(0))
+		gomega.Expect(s.Count()).To(gomega.Equal(2))
+		gomega.Expect(r.reads).To(gomega.BeNumerically(">", 0))
+	})
+})
@@ -0,0 +1,78 @@
+package util
+
+import (
+	"hash/fnv"
+	"math"
+)
+
+// BloomFilter is a probabilistic set of strings using a fixed amount of memory
+// It never misses an added string, but may report a string as present while it was never added
+type BloomFilter struct {
+	bits   []uint64
+	size   uint64
+	hashes int
+}
+
+// NewBloomFilter returns a Bloom filter sized to hold a number of strings with a given false positive rate
+//
+// @param expectedN	Expected number of strings to be added
+// @param fpRate	Expected false positive rate once expectedN strings are added, between 0 and 1 exclusive
+// @return			An empty Bloom filter
+func NewBloomFilter(expectedN int, fpRate float64) *BloomFilter {
+	if expectedN < 1 {
+		expectedN = 1
+	}
+	if fpRate <= 0 || fpRate >= 1 {
+		panic("False positive rate should be between 0 and 1 exclusive")
+	}
+	size := uint64(math.Ceil(-float64(expectedN) * math.Log(fpRate) / (math.Ln2 * math.Ln2)))
+	hashes := int(math.Round(float64(size) / float64(expectedN) * math.Ln2))
+	if hashes < 1 {
+		hashes = 1
+	}
+	return &BloomFilter{
+		bits:   make([]uint64, (size+63)/64),
+		size:   size,
+		hashes: hashes,
+	}
+}
+
+// Add puts a string into the filter
+func (f *BloomFilter) Add(key string) {
+	f.TestAndAdd(key)
+}
+
+// Test returns true if a string may have been added, false if it has surely not
+func (f *BloomFilter) Test(key string) bool {
+	h1, h2 := bloomHashes(key)
+	for i := 0; i < f.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % f.size
+		if f.bits[bit>>6]&(1<<(bit&63)) == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// TestAndAdd puts a string into the filter and returns whether it may have been added before
+func (f *BloomFilter) TestAndAdd(key string) bool {
+	h1, h2 := bloomHashes(key)
+	present := true
+	for i := 0; i < f.hashes; i++ {
+		bit := (h1 + uint64(i)*h2) % f.size
+		if f.bits[bit>>6]&(1<<(bit&63)) == 0 {
+			present = false
+			f.bits[bit>>6] |= 1 << (bit & 63)
+		}
+	}
+	return present
+}
+
+// bloomHashes derives the two hashes combined into as many as needed by double hashing
+func bloomHashes(key string) (uint64, uint64) {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	h1 := h.Sum64()
+	h2 := h1*0x9e3779b97f4a7c15 ^ h1>>29
+	return h1, h2 | 1
+}
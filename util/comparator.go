@@ -0,0 +1,137 @@
+package util
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Comparator judges if a is smaller than b
+// It can be passed wherever a less function is expected, like Sorted, Max or Min
+type Comparator func(a, b interface{}) bool
+
+// NaturalOrder compares values of built-in ordered types, i.e. integers, floats and strings, or types based on them
+// Both values should be of the same type, otherwise it panics
+var NaturalOrder Comparator = naturalLess
+
+// ReverseOrder compares values of built-in ordered types in descending order
+var ReverseOrder Comparator = NaturalOrder.Reversed()
+
+// Comparing returns a comparator ordering items by the natural order of a key
+//
+// @param key	Function to extract the key of an item
+// @return		A comparator on keys
+func Comparing(key func(interface{}) interface{}) Comparator {
+	return ComparingWith(key, NaturalOrder)
+}
+
+// ComparingWith returns a comparator ordering items by a key compared with a given function
+//
+// @param key	Function to extract the key of an item
+// @param less	Function to judge which key is smaller
+// @return		A comparator on keys
+func ComparingWith(key func(interface{}) interface{}, less func(a, b interface{}) bool) Comparator {
+	return func(a, b interface{}) bool {
+		return less(key(a), key(b))
+	}
+}
+
+// ThenComparing returns a comparator falling back on another one when this one considers two items equal
+//
+// @param next	Comparator used on items equal for this one
+// @return		A composed comparator
+func (c Comparator) ThenComparing(next func(a, b interface{}) bool) Comparator {
+	return func(a, b interface{}) bool {
+		if c(a, b) {
+			return true
+		}
+		if c(b, a) {
+			return false
+		}
+		return next(a, b)
+	}
+}
+
+// ThenComparingKey returns a comparator falling back on the natural order of a key when this one considers two items equal
+//
+// @param key	Function to extract the key of an item
+// @return		A composed comparator
+func (c Comparator) ThenComparingKey(key func(interface{}) interface{}) Comparator {
+	return c.ThenComparing(Comparing(key))
+}
+
+// Reversed returns a comparator imposing the reverse order of this one
+//
+// @return	A reversed comparator
+func (c Comparator) Reversed() Comparator {
+	return func(a, b interface{}) bool {
+		return c(b, a)
+	}
+}
+
+// NullsFirst returns a comparator considering nil smaller than any other item, and comparing other items with this one
+// Nil interfaces as well as nil pointers, maps, slices, channels and functions are considered nil
+//
+// @return	A nil-friendly comparator
+func (c Comparator) NullsFirst() Comparator {
+	return func(a, b interface{}) bool {
+		aNil, bNil := isNil(a), isNil(b)
+		if aNil || bNil {
+			return aNil && !bNil
+		}
+		return c(a, b)
+	}
+}
+
+// NullsLast returns a comparator considering nil greater than any other item, and comparing other items with this one
+// Nil interfaces as well as nil pointers, maps, slices, channels and functions are considered nil
+//
+// @return	A nil-friendly comparator
+func (c Comparator) NullsLast() Comparator {
+	return func(a, b interface{}) bool {
+		aNil, bNil := isNil(a), isNil(b)
+		if aNil || bNil {
+			return bNil && !aNil
+		}
+		return c(a, b)
+	}
+}
+
+func isNil(item interface{}) bool {
+	if item == nil {
+		return true
+	}
+	v := reflect.ValueOf(item)
+	switch v.Kind() {
+	case reflect.Ptr, reflect.Map, reflect.Slice, reflect.Chan, reflect.Func, reflect.Interface:
+		return v.IsNil()
+	}
+	return false
+}
+
+func naturalLess(a, b interface{}) bool {
+	switch x := a.(type) {
+	case int:
+		return x < b.(int)
+	case int64:
+		return x < b.(int64)
+	case float64:
+		return x < b.(float64)
+	case string:
+		return x < b.(string)
+	}
+	va, vb := reflect.ValueOf(a), reflect.ValueOf(b)
+	if va.Type() != vb.Type() {
+		panic(fmt.Sprintf("Cannot compare values of different types %v and %v", va.Type(), vb.Type()))
+	}
+	switch va.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return va.Int() < vb.Int()
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return va.Uint() < vb.Uint()
+	case reflect.Float32, reflect.Float64:
+		return va.Float() < vb.Float()
+	case reflect.String:
+		return va.String() < vb.String()
+	}
+	panic(fmt.Sprintf("Type %v has no natural order", va.Type()))
+}
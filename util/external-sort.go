@@ -0,0 +1,331 @@
+package util
+
+import (
+	"bufio"
+	"container/heap"
+	"encoding/gob"
+	"fmt"
+	"io"
+	"os"
+)
+
+// Encoder writes data items into a run file of an external sort
+type Encoder interface {
+	Encode(item interface{}) error
+}
+
+// Decoder reads back data items written by the matching Encoder, returning io.EOF once all are read
+type Decoder interface {
+	Decode() (interface{}, error)
+}
+
+// Codec creates encoders and decoders of run files in an external sort
+type Codec interface {
+	NewEncoder(w io.Writer) Encoder
+	NewDecoder(r io.Reader) Decoder
+}
+
+// GobCodec encodes data items with encoding/gob
+// Concrete types other than Go's basic ones must be registered with gob.Register beforehand
+var GobCodec Codec = gobCodec{}
+
+type gobCodec struct{}
+
+func (gobCodec) NewEncoder(w io.Writer) Encoder {
+	return gobEncoder{gob.NewEncoder(w)}
+}
+
+func (gobCodec) NewDecoder(r io.Reader) Decoder {
+	return gobDecoder{gob.NewDecoder(r)}
+}
+
+type gobEncoder struct {
+	encoder *gob.Encoder
+}
+
+func (e gobEncoder) Encode(item interface{}) error {
+	return e.encoder.Encode(&item)
+}
+
+type gobDecoder struct {
+	decoder *gob.Decoder
+}
+
+func (d gobDecoder) Decode() (interface{}, error) {
+	var item interface{}
+	err := d.decoder.Decode(&item)
+	return item, err
+}
+
+// defaultRunSize is the number of items sorted in memory at once when none is given
+const defaultRunSize = 1 << 16
+
+// ExternalSortOptions configures an external sort
+type ExternalSortOptions struct {
+	// RunSize is the maximal number of items sorted in memory at once
+	// Data no longer than it is sorted in memory without touching the disk
+	RunSize int
+	// Dir is where run files are created, the default temporary directory if empty
+	Dir string
+	// Codec encodes items into run files, GobCodec if nil
+	Codec Codec
+	// Sorter sorts every run, MergeSorter if nil
+	Sorter Sorter
+	// OnError receives errors met by ExternalSorter while writing or reading run files, items being sorted in memory instead
+	// ExternalSort returns such errors rather than reporting them
+	OnError func(err error)
+}
+
+// ExternalSorter returns a sorter spilling sorted runs to temporary files before merging them back
+// If run files cannot be written or read, the error is reported to options.OnError, if any, and items are sorted in memory
+// so that no item is ever lost
+func ExternalSorter(options ExternalSortOptions) Sorter {
+	return SorterFunc(func(arr []interface{}, less func(a, b interface{}) bool) []interface{} {
+		result, err := sortExternally(arr, less, options)
+		if err != nil {
+			if options.OnError != nil {
+				options.OnError(fmt.Errorf("external sort failed, sorting in memory: %w", err))
+			}
+			return runSorter(options).Sort(arr, less)
+		}
+		return result
+	})
+}
+
+func sortExternally(arr []interface{}, less func(a, b interface{}) bool, options ExternalSortOptions) ([]interface{}, error) {
+	next := 0
+	it, err := ExternalSort(func() (interface{}, bool) {
+		if next == len(arr) {
+			return nil, false
+		}
+		next++
+		return arr[next-1], true
+	}, less, options)
+	if err != nil {
+		return nil, err
+	}
+	defer it.Close()
+	result := make([]interface{}, 0, len(arr))
+	for it.Next() {
+		result = append(result, it.Item())
+	}
+	return result, it.Err()
+}
+
+func runSorter(options ExternalSortOptions) Sorter {
+	if options.Sorter == nil {
+		return MergeSorter
+	}
+	return options.Sorter
+}
+
+// ExternalSort reads items from next until it reports there is none left, sorting them by runs of bounded size
+// Every full run is written to a temporary file as soon as it is read, so that at most a run is held in memory,
+// the last run being kept in memory rather than written
+// Runs are merged back lazily by the returned iterator, which holds a single item per run at once
+// Order of equal items is kept if the run sorter is stable
+//
+// @param next		Function returning the next item to sort, and false once there is none left
+// @param less		Function to judge which value is smaller
+// @param options	Sorting options
+// @return			An iterator over sorted items, to be closed so that run files are removed, or the error met writing a run
+func ExternalSort(next func() (interface{}, bool), less func(a, b interface{}) bool, options ExternalSortOptions) (*SortedIterator, error) {
+	runSize := options.RunSize
+	if runSize <= 0 {
+		runSize = defaultRunSize
+	}
+	sorter := runSorter(options)
+	codec := options.Codec
+	if codec == nil {
+		codec = GobCodec
+	}
+	it := &SortedIterator{heads: &runHeap{less: less}}
+	var run []interface{}
+	for item, ok := next(); ok; item, ok = next() {
+		// A full run is only spilled once another item shows up, so that data fitting in a run never touches the disk
+		if len(run) == runSize {
+			if err := it.spill(sorter.Sort(run, less), options.Dir, codec); err != nil {
+				it.Close()
+				return nil, err
+			}
+			// Drop references to spilled items, so that they can be released
+			for i := range run {
+				run[i] = nil
+			}
+			run = run[:0]
+		}
+		run = append(run, item)
+	}
+	if len(run) > 0 {
+		it.runs = append(it.runs, &memoryRun{items: sorter.Sort(run, less)})
+	}
+	if err := it.start(); err != nil {
+		it.Close()
+		return nil, err
+	}
+	return it, nil
+}
+
+// SortedIterator merges sorted runs of an external sort lazily
+type SortedIterator struct {
+	runs  []sortedRun
+	files []*os.File
+	heads *runHeap
+	item  interface{}
+	err   error
+}
+
+type sortedRun interface {
+	// next returns the next item of the run, or io.EOF once every item is read
+	next() (interface{}, error)
+}
+
+type memoryRun struct {
+	items []interface{}
+}
+
+func (r *memoryRun) next() (interface{}, error) {
+	if len(r.items) == 0 {
+		return nil, io.EOF
+	}
+	item := r.items[0]
+	r.items[0] = nil
+	r.items = r.items[1:]
+	return item, nil
+}
+
+type fileRun struct {
+	decoder Decoder
+}
+
+func (r *fileRun) next() (interface{}, error) {
+	return r.decoder.Decode()
+}
+
+func (it *SortedIterator) spill(run []interface{}, dir string, codec Codec) error {
+	file, err := os.CreateTemp(dir, "stream-sort-*")
+	if err != nil {
+		return err
+	}
+	it.files = append(it.files, file)
+	if err := writeRun(file, codec, run); err != nil {
+		return err
+	}
+	if _, err := file.Seek(0, io.SeekStart); err != nil {
+		return err
+	}
+	it.runs = append(it.runs, &fileRun{decoder: codec.NewDecoder(bufio.NewReader(file))})
+	return nil
+}
+
+func writeRun(file *os.File, codec Codec, run []interface{}) error {
+	writer := bufio.NewWriter(file)
+	encoder := codec.NewEncoder(writer)
+	for _, item := range run {
+		if err := encoder.Encode(item); err != nil {
+			return err
+		}
+	}
+	return writer.Flush()
+}
+
+func (it *SortedIterator) start() error {
+	for i, run := range it.runs {
+		item, err := run.next()
+		if err == io.EOF {
+			continue
+		}
+		if err != nil {
+			return err
+		}
+		it.heads.heads = append(it.heads.heads, runHead{item: item, run: i})
+	}
+	heap.Init(it.heads)
+	return nil
+}
+
+// Next moves to the next sorted item, returning false once every item is read or an error is met
+func (it *SortedIterator) Next() bool {
+	if it.err != nil || it.heads.Len() == 0 {
+		it.item = nil
+		return false
+	}
+	head := it.heads.heads[0]
+	it.item = head.item
+	item, err := it.runs[head.run].next()
+	switch {
+	case err == io.EOF:
+		heap.Pop(it.heads)
+	case err != nil:
+		it.err = err
+	default:
+		it.heads.heads[0].item = item
+		heap.Fix(it.heads, 0)
+	}
+	return true
+}
+
+// Item returns the current item, once Next returned true
+func (it *SortedIterator) Item() interface{} {
+	return it.item
+}
+
+// Err returns the error met reading a run file, if any, once Next returned false
+func (it *SortedIterator) Err() error {
+	return it.err
+}
+
+// Close removes run files, and can be called more than once
+func (it *SortedIterator) Close() error {
+	var first error
+	for _, file := range it.files {
+		if err := file.Close(); err != nil && first == nil {
+			first = err
+		}
+		if err := os.Remove(file.Name()); err != nil && first == nil {
+			first = err
+		}
+	}
+	it.files = nil
+	return first
+}
+
+type runHead struct {
+	item interface{}
+	run  int
+}
+
+// runHeap orders run heads by item, then by run so that equal items come out in their original order
+type runHeap struct {
+	heads []runHead
+	less  func(a, b interface{}) bool
+}
+
+func (h *runHeap) Len() int {
+	return len(h.heads)
+}
+
+func (h *runHeap) Less(i, j int) bool {
+	a, b := h.heads[i], h.heads[j]
+	if h.less(a.item, b.item) {
+		return true
+	}
+	if h.less(b.item, a.item) {
+		return false
+	}
+	return a.run < b.run
+}
+
+func (h *runHeap) Swap(i, j int) {
+	h.heads[i], h.heads[j] = h.heads[j], h.heads[i]
+}
+
+func (h *runHeap) Push(x interface{}) {
+	h.heads = append(h.heads, x.(runHead))
+}
+
+func (h *runHeap) Pop() interface{} {
+	last := h.heads[len(h.heads)-1]
+	h.heads = h.heads[:len(h.heads)-1]
+	return last
+}
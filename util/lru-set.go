@@ -0,0 +1,40 @@
+package util
+
+import "container/list"
+
+// LRUSet is a set remembering at most a given number of keys, forgetting the least recently seen ones first
+type LRUSet struct {
+	capacity int
+	order    *list.List
+	elements map[interface{}]*list.Element
+}
+
+// NewLRUSet returns an empty set remembering at most capacity keys
+func NewLRUSet(capacity int) *LRUSet {
+	if capacity < 1 {
+		panic("LRU set capacity should be greater than 0")
+	}
+	return &LRUSet{
+		capacity: capacity,
+		order:    list.New(),
+		elements: make(map[interface{}]*list.Element, capacity),
+	}
+}
+
+// TestAndAdd marks a key as the most recently seen one and returns whether it was remembered before
+func (s *LRUSet) TestAndAdd(key interface{}) bool {
+	if element, ok := s.elements[key]; ok {
+		s.order.MoveToFront(element)
+		return true
+	}
+	s.elements[key] = s.order.PushFront(key)
+	if s.order.Len() > s.capacity {
+		delete(s.elements, s.order.Remove(s.order.Back()))
+	}
+	return false
+}
+
+// Len returns the number of keys remembered
+func (s *LRUSet) Len() int {
+	return s.order.Len()
+}
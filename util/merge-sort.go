@@ -1,42 +1,142 @@
 package util
 
+import (
+	"runtime"
+	"sync"
+)
+
+// mergeSortCutoff is the minimal number of items sorted sequentially by a single go routine
+const mergeSortCutoff = 1 << 11
+
+// MergeSort returns data items sorted with a parallel merge sort using as many go routines as available processors
+// Data is left untouched, items being sorted in a copy
 func MergeSort(data []interface{}, less func(prev, cur interface{}) bool) []interface{} {
+	return ParallelMergeSort(append([]interface{}{}, data...), less, runtime.GOMAXPROCS(0))
+}
+
+// ParallelMergeSorter returns a sorter running a parallel merge sort with at most the given number of go routines
+func ParallelMergeSorter(routines int) Sorter {
+	return SorterFunc(func(arr []interface{}, less func(a, b interface{}) bool) []interface{} {
+		return ParallelMergeSort(arr, less, routines)
+	})
+}
+
+// ParallelMergeSort sorts data items with a merge sort using at most the given number of go routines, keeping the order of equal items
+// Data is split into at most one chunk per go routine, never smaller than a cutoff, which are sorted sequentially and then merged in parallel
+// A single buffer as long as data is used throughout the sort, and data is sorted in place
+func ParallelMergeSort(data []interface{}, less func(prev, cur interface{}) bool, routines int) []interface{} {
 	length := len(data)
-	if length < 2 {
-		return data
+	chunks := (length + mergeSortCutoff - 1) / mergeSortCutoff
+	if routines < chunks {
+		chunks = routines
 	}
-	half := length >> 1
-	ch1 := make(chan []interface{})
-	ch2 := make(chan []interface{})
-	go func(arr []interface{}) {
-		ch1 <- MergeSort(arr, less)
-	}(data[:half])
-	go func(arr []interface{}) {
-		ch2 <- MergeSort(arr, less)
-	}(data[half:])
-	return merge(<-ch1, <-ch2, less)
+	if chunks <= 1 {
+		return StableSort(data, less)
+	}
+	bounds := make([]int, chunks+1)
+	for i := range bounds {
+		bounds[i] = length * i / chunks
+	}
+	buf := make([]interface{}, length)
+	parallelFor(routines, chunks, func(i int) {
+		stableSort(data[bounds[i]:bounds[i+1]], buf[bounds[i]:bounds[i+1]], less)
+	})
+
+	src, dst := data, buf
+	for len(bounds) > 2 {
+		runs := len(bounds) - 1
+		parallelFor(routines, (runs+1)/2, func(i int) {
+			lo := bounds[2*i]
+			if 2*i+1 == runs {
+				copy(dst[lo:], src[lo:bounds[2*i+1]])
+				return
+			}
+			mid, hi := bounds[2*i+1], bounds[2*i+2]
+			mergeInto(dst[lo:hi], src[lo:mid], src[mid:hi], less)
+		})
+		next := bounds[:0]
+		for i := 0; i < runs; i += 2 {
+			next = append(next, bounds[i])
+		}
+		bounds = append(next, length)
+		src, dst = dst, src
+	}
+	if &src[0] != &data[0] {
+		copy(data, src)
+	}
+	return data
 }
 
-func merge(a, b []interface{}, less func(prev, cur interface{}) bool) []interface{} {
-	var result []interface{}
-	i := 0
-	j := 0
-	lenA := len(a)
-	lenB := len(b)
-	for i < lenA && j < lenB {
-		if less(a[i], b[j]) {
-			result = append(result, a[i])
-			i++
-		} else {
-			result = append(result, b[i])
+// mergeInto merges two sorted arrays into dst, taking items from a first when equal
+func mergeInto(dst, a, b []interface{}, less func(prev, cur interface{}) bool) {
+	i, j, k := 0, 0, 0
+	for i < len(a) && j < len(b) {
+		if less(b[j], a[i]) {
+			dst[k] = b[j]
 			j++
+		} else {
+			dst[k] = a[i]
+			i++
 		}
+		k++
+	}
+	k += copy(dst[k:], a[i:])
+	copy(dst[k:], b[j:])
+}
+
+// parallelFor runs task for every index in [0, tasks) with at most the given number of go routines
+func parallelFor(routines int, tasks int, task func(int)) {
+	if routines > tasks {
+		routines = tasks
+	}
+	indexes := make(chan int, tasks)
+	for i := 0; i < tasks; i++ {
+		indexes <- i
+	}
+	close(indexes)
+	var wg sync.WaitGroup
+	wg.Add(routines)
+	for r := 0; r < routines; r++ {
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				task(i)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
+// StableSort sorts data items with a sequential merge sort, keeping the order of equal items
+func StableSort(arr []interface{}, less func(a, b interface{}) bool) []interface{} {
+	stableSort(arr, make([]interface{}, len(arr)>>1+1), less)
+	return arr
+}
+
+func stableSort(arr []interface{}, buf []interface{}, less func(a, b interface{}) bool) {
+	length := len(arr)
+	if length <= 12 {
+		insertionSort(arr, less)
+		return
 	}
-	for ; i < lenA; i++ {
-		result = append(result, a[i])
+	half := length >> 1
+	stableSort(arr[:half], buf, less)
+	stableSort(arr[half:], buf, less)
+	if !less(arr[half], arr[half-1]) {
+		return
 	}
-	for ; j < lenB; j++ {
-		result = append(result, b[j])
+	left := buf[:half]
+	copy(left, arr[:half])
+	i, j, k := 0, half, 0
+	for i < half && j < length {
+		if less(arr[j], left[i]) {
+			arr[k] = arr[j]
+			j++
+		} else {
+			arr[k] = left[i]
+			i++
+		}
+		k++
 	}
-	return result
+	copy(arr[k:], left[i:])
 }
@@ -0,0 +1,91 @@
+package util
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+)
+
+// errNilOptional is returned when decoding into a nil Optional, which has nowhere to keep the value
+var errNilOptional = errors.New("cannot decode into a nil Optional")
+
+// MarshalJSON encodes the value if present, null otherwise
+func (opt *Optional) MarshalJSON() ([]byte, error) {
+	if !opt.IsPresent() {
+		return []byte("null"), nil
+	}
+	return json.Marshal(opt.data)
+}
+
+// UnmarshalJSON empties the Optional on null, and decodes the value otherwise
+// If the Optional holds a pointer, the value is decoded into it, otherwise it is decoded as into an interface{}
+func (opt *Optional) UnmarshalJSON(data []byte) error {
+	if opt == nil {
+		return errNilOptional
+	}
+	if string(data) == "null" {
+		opt.data = nil
+		return nil
+	}
+	return json.Unmarshal(data, &opt.data)
+}
+
+// MarshalText encodes the value if present, using its own text encoding if any, and returns empty text otherwise
+func (opt *Optional) MarshalText() ([]byte, error) {
+	if !opt.IsPresent() {
+		return []byte{}, nil
+	}
+	if marshaler, ok := opt.data.(encoding.TextMarshaler); ok {
+		return marshaler.MarshalText()
+	}
+	return []byte(fmt.Sprint(opt.data)), nil
+}
+
+// UnmarshalText empties the Optional on empty text
+// Otherwise the text is decoded into the value held if it implements encoding.TextUnmarshaler, and kept as a string if not
+func (opt *Optional) UnmarshalText(text []byte) error {
+	if opt == nil {
+		return errNilOptional
+	}
+	if len(text) == 0 {
+		opt.data = nil
+		return nil
+	}
+	if unmarshaler, ok := opt.data.(encoding.TextUnmarshaler); ok {
+		return unmarshaler.UnmarshalText(text)
+	}
+	opt.data = string(text)
+	return nil
+}
+
+// Scan implements sql.Scanner, NULL emptying the Optional
+// If the Optional holds a sql.Scanner, the column is scanned into it
+func (opt *Optional) Scan(src interface{}) error {
+	if opt == nil {
+		return errNilOptional
+	}
+	if src == nil {
+		opt.data = nil
+		return nil
+	}
+	if scanner, ok := opt.data.(sql.Scanner); ok {
+		return scanner.Scan(src)
+	}
+	if b, ok := src.([]byte); ok {
+		// Drivers may reuse the buffer once Scan returns
+		src = append([]byte{}, b...)
+	}
+	opt.data = src
+	return nil
+}
+
+// Value implements driver.Valuer, an empty Optional being written as NULL
+func (opt *Optional) Value() (driver.Value, error) {
+	if !opt.IsPresent() {
+		return nil, nil
+	}
+	return driver.DefaultParameterConverter.ConvertValue(opt.data)
+}
@@ -30,8 +30,9 @@ func OfEmpty() *Optional {
 	return &Optional{}
 }
 
+// IsPresent returns false for a nil Optional, like the one a JSON null leaves in a pointer field, so that every reading method is safe on it
 func (opt *Optional) IsPresent() bool {
-	return opt.data != nil
+	return opt != nil && opt.data != nil
 }
 
 func (opt *Optional) Get() interface{} {
@@ -63,7 +64,10 @@ func (opt *Optional) OrElseError(err func() error) (interface{}, error) {
 }
 
 func (opt *Optional) Filter(filter func(data interface{}) bool) *Optional {
-	if !opt.IsPresent() || filter(opt.Get()) {
+	if !opt.IsPresent() {
+		return OfEmpty()
+	}
+	if filter(opt.Get()) {
 		return opt
 	}
 	return OfEmpty()
@@ -71,14 +75,14 @@ func (opt *Optional) Filter(filter func(data interface{}) bool) *Optional {
 
 func (opt *Optional) Map(mapper func(data interface{}) interface{}) *Optional {
 	if !opt.IsPresent() {
-		return opt
+		return OfEmpty()
 	}
 	return OfNillable(mapper(opt.Get()))
 }
 
 func (opt *Optional) FlatMap(mapper func(data interface{}) *Optional) *Optional {
 	if !opt.IsPresent() {
-		return opt
+		return OfEmpty()
 	}
 	return mapper(opt.Get())
 }
@@ -89,9 +93,10 @@ func (opt *Optional) IfPresent(consumer func(data interface{})) {
 	}
 }
 
+// String formats the value if present, and returns Optional.empty otherwise, so that an empty Optional is told apart from an empty string
 func (opt *Optional) String() string {
 	if !opt.IsPresent() {
-		return ""
+		return "Optional.empty"
 	}
 	return fmt.Sprint(opt.data)
 }
@@ -0,0 +1,241 @@
+package util
+
+import "math/bits"
+
+// PdqSort sorts data items in place with pattern-defeating quicksort
+// It runs in O(n log n) in the worst case and in linear time on some patterns like sorted or reversed data
+// Order of equal items is not kept
+func PdqSort(arr []interface{}, less func(a, b interface{}) bool) []interface{} {
+	sorter := pdqSorter{
+		arr:  arr,
+		less: less,
+	}
+	sorter.sort(0, len(arr), bits.Len(uint(len(arr))))
+	return arr
+}
+
+type sortedHint int
+
+const (
+	unknownHint sortedHint = iota
+	increasingHint
+	decreasingHint
+)
+
+type pdqSorter struct {
+	arr  []interface{}
+	less func(a, b interface{}) bool
+}
+
+func (s pdqSorter) swap(i, j int) {
+	s.arr[i], s.arr[j] = s.arr[j], s.arr[i]
+}
+
+func (s pdqSorter) lessAt(i, j int) bool {
+	return s.less(s.arr[i], s.arr[j])
+}
+
+func (s pdqSorter) sort(a, b, limit int) {
+	const maxInsertion = 12
+	wasBalanced := true
+	wasPartitioned := true
+	for {
+		length := b - a
+		if length <= maxInsertion {
+			insertionSort(s.arr[a:b], s.less)
+			return
+		}
+		if limit == 0 {
+			HeapSort(s.arr[a:b], s.less)
+			return
+		}
+		if !wasBalanced {
+			s.breakPatterns(a, b)
+			limit--
+		}
+
+		pivot, hint := s.choosePivot(a, b)
+		if hint == decreasingHint {
+			s.reverse(a, b)
+			pivot = (b - 1) - (pivot - a)
+			hint = increasingHint
+		}
+		if wasBalanced && wasPartitioned && hint == increasingHint && s.partialInsertionSort(a, b) {
+			return
+		}
+		// The item right before this range is a former pivot, no greater than any item in the range
+		// If it equals the new pivot, items equal to the pivot are put aside at once
+		if a > 0 && !s.lessAt(a-1, pivot) {
+			a = s.partitionEqual(a, b, pivot)
+			continue
+		}
+
+		mid, alreadyPartitioned := s.partition(a, b, pivot)
+		wasPartitioned = alreadyPartitioned
+		left, right := mid-a, b-mid
+		threshold := length / 8
+		if left < right {
+			wasBalanced = left >= threshold
+			s.sort(a, mid, limit)
+			a = mid + 1
+		} else {
+			wasBalanced = right >= threshold
+			s.sort(mid+1, b, limit)
+			b = mid
+		}
+	}
+}
+
+func (s pdqSorter) partition(a, b, pivot int) (int, bool) {
+	s.swap(a, pivot)
+	i, j := a+1, b-1
+	for i <= j && s.lessAt(i, a) {
+		i++
+	}
+	for i <= j && !s.lessAt(j, a) {
+		j--
+	}
+	if i > j {
+		s.swap(j, a)
+		return j, true
+	}
+	s.swap(i, j)
+	i++
+	j--
+	for {
+		for i <= j && s.lessAt(i, a) {
+			i++
+		}
+		for i <= j && !s.lessAt(j, a) {
+			j--
+		}
+		if i > j {
+			break
+		}
+		s.swap(i, j)
+		i++
+		j--
+	}
+	s.swap(j, a)
+	return j, false
+}
+
+func (s pdqSorter) partitionEqual(a, b, pivot int) int {
+	s.swap(a, pivot)
+	i, j := a+1, b-1
+	for {
+		for i <= j && !s.lessAt(a, i) {
+			i++
+		}
+		for i <= j && s.lessAt(a, j) {
+			j--
+		}
+		if i > j {
+			break
+		}
+		s.swap(i, j)
+		i++
+		j--
+	}
+	return i
+}
+
+// partialInsertionSort sorts nearly sorted ranges, giving up after a few misplaced items
+func (s pdqSorter) partialInsertionSort(a, b int) bool {
+	const (
+		maxSteps         = 5
+		shortestShifting = 50
+	)
+	i := a + 1
+	for step := 0; step < maxSteps; step++ {
+		for i < b && !s.lessAt(i, i-1) {
+			i++
+		}
+		if i == b {
+			return true
+		}
+		if b-a < shortestShifting {
+			return false
+		}
+		s.swap(i, i-1)
+		for j := i - 1; j > a && s.lessAt(j, j-1); j-- {
+			s.swap(j, j-1)
+		}
+		for j := i + 1; j < b && s.lessAt(j, j-1); j++ {
+			s.swap(j, j-1)
+		}
+	}
+	return false
+}
+
+// breakPatterns swaps a few items around to defeat patterns leading to unbalanced partitions
+func (s pdqSorter) breakPatterns(a, b int) {
+	length := b - a
+	if length < 8 {
+		return
+	}
+	random := uint64(length)
+	modulus := uint64(1) << bits.Len(uint(length))
+	idx := a + (length/4)*2 - 1
+	for i := 0; i < 3; i++ {
+		random ^= random << 13
+		random ^= random >> 7
+		random ^= random << 17
+		other := int(random & (modulus - 1))
+		if other >= length {
+			other -= length
+		}
+		s.swap(idx-1+i, a+other)
+	}
+}
+
+// choosePivot picks the median of three items, or the median of three medians on long ranges
+// The hint tells if the sampled items looked already sorted in either direction
+func (s pdqSorter) choosePivot(a, b int) (int, sortedHint) {
+	const (
+		shortestNinther = 50
+		maxSwaps        = 4 * 3
+	)
+	length := b - a
+	swaps := 0
+	i := a + length/4*1
+	j := a + length/4*2
+	k := a + length/4*3
+	if length >= 8 {
+		if length >= shortestNinther {
+			i = s.median(i-1, i, i+1, &swaps)
+			j = s.median(j-1, j, j+1, &swaps)
+			k = s.median(k-1, k, k+1, &swaps)
+		}
+		j = s.median(i, j, k, &swaps)
+	}
+	switch swaps {
+	case 0:
+		return j, increasingHint
+	case maxSwaps:
+		return j, decreasingHint
+	default:
+		return j, unknownHint
+	}
+}
+
+func (s pdqSorter) order(a, b int, swaps *int) (int, int) {
+	if s.lessAt(b, a) {
+		*swaps++
+		return b, a
+	}
+	return a, b
+}
+
+func (s pdqSorter) median(a, b, c int, swaps *int) int {
+	a, b = s.order(a, b, swaps)
+	b, c = s.order(b, c, swaps)
+	_, b = s.order(a, b, swaps)
+	return b
+}
+
+func (s pdqSorter) reverse(a, b int) {
+	for i, j := a, b-1; i < j; i, j = i+1, j-1 {
+		s.swap(i, j)
+	}
+}
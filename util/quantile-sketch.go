@@ -0,0 +1,133 @@
+package util
+
+import (
+	"math"
+	"sort"
+)
+
+// QuantileSketch estimates quantiles of a sequence of numbers in bounded memory, following the KLL algorithm
+// Values are kept in levels, a value at level l standing for 2^l original ones
+// When a level is full, it is sorted and every other value is promoted to the next level
+type QuantileSketch struct {
+	k      int
+	levels [][]float64
+	count  int
+	min    float64
+	max    float64
+	// coin alternates which half of a level is promoted, which keeps the sketch deterministic
+	coin int
+}
+
+// NewQuantileSketch returns an empty sketch
+//
+// @param k	Capacity of the top level, the error decreasing roughly as 1/k
+// @return	An empty sketch
+func NewQuantileSketch(k int) *QuantileSketch {
+	if k < 8 {
+		k = 8
+	}
+	return &QuantileSketch{
+		k:      k,
+		levels: [][]float64{nil},
+		min:    math.Inf(1),
+		max:    math.Inf(-1),
+	}
+}
+
+// Add puts a value into the sketch
+func (s *QuantileSketch) Add(value float64) {
+	s.levels[0] = append(s.levels[0], value)
+	s.count++
+	s.min = math.Min(s.min, value)
+	s.max = math.Max(s.max, value)
+	s.compress()
+}
+
+// Merge puts every value of another sketch into this one
+func (s *QuantileSketch) Merge(other *QuantileSketch) {
+	for len(s.levels) < len(other.levels) {
+		s.levels = append(s.levels, nil)
+	}
+	for l, values := range other.levels {
+		s.levels[l] = append(s.levels[l], values...)
+	}
+	s.count += other.count
+	s.min = math.Min(s.min, other.min)
+	s.max = math.Max(s.max, other.max)
+	s.compress()
+}
+
+// Count returns the number of values added
+func (s *QuantileSketch) Count() int {
+	return s.count
+}
+
+// Quantile returns an estimate of the value below which a fraction q of the values fall, NaN if the sketch is empty
+func (s *QuantileSketch) Quantile(q float64) float64 {
+	if s.count == 0 {
+		return math.NaN()
+	}
+	if q <= 0 {
+		return s.min
+	}
+	if q >= 1 {
+		return s.max
+	}
+	type weighted struct {
+		value  float64
+		weight int
+	}
+	var all []weighted
+	total := 0
+	for l, values := range s.levels {
+		for _, value := range values {
+			all = append(all, weighted{value, 1 << l})
+			total += 1 << l
+		}
+	}
+	sort.Slice(all, func(i, j int) bool {
+		return all[i].value < all[j].value
+	})
+	target := q * float64(total)
+	cumulative := 0
+	for _, item := range all {
+		cumulative += item.weight
+		if float64(cumulative) >= target {
+			return item.value
+		}
+	}
+	return s.max
+}
+
+// capacity shrinks geometrically from the top level down, without going below 2
+func (s *QuantileSketch) capacity(level int) int {
+	depth := len(s.levels) - level - 1
+	c := int(math.Ceil(float64(s.k) * math.Pow(2.0/3.0, float64(depth))))
+	if c < 2 {
+		return 2
+	}
+	return c
+}
+
+func (s *QuantileSketch) compress() {
+	for l := 0; l < len(s.levels); l++ {
+		if len(s.levels[l]) < s.capacity(l) {
+			continue
+		}
+		if l+1 == len(s.levels) {
+			s.levels = append(s.levels, nil)
+		}
+		values := s.levels[l]
+		sort.Float64s(values)
+		var kept []float64
+		if len(values)%2 == 1 {
+			kept = []float64{values[len(values)-1]}
+			values = values[:len(values)-1]
+		}
+		for i := s.coin; i < len(values); i += 2 {
+			s.levels[l+1] = append(s.levels[l+1], values[i])
+		}
+		s.coin ^= 1
+		s.levels[l] = kept
+	}
+}
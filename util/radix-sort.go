@@ -0,0 +1,50 @@
+package util
+
+// RadixSorter returns a sorter ordering data items by an integer key with a stable LSD radix sort
+// The less function given to Sort is ignored, order being entirely decided by keys
+func RadixSorter(key func(interface{}) int64) Sorter {
+	return SorterFunc(func(arr []interface{}, less func(a, b interface{}) bool) []interface{} {
+		return RadixSort(arr, key)
+	})
+}
+
+// RadixSort sorts data items in ascending order of an integer key, keeping the order of items with equal keys
+func RadixSort(arr []interface{}, key func(interface{}) int64) []interface{} {
+	length := len(arr)
+	if length < 2 {
+		return arr
+	}
+	// Flipping the sign bit makes negative keys sort before positive ones as unsigned integers
+	keys := make([]uint64, length)
+	for i, item := range arr {
+		keys[i] = uint64(key(item)) ^ (1 << 63)
+	}
+	items := make([]interface{}, length)
+	copy(items, arr)
+	bufItems := make([]interface{}, length)
+	bufKeys := make([]uint64, length)
+	for shift := uint(0); shift < 64; shift += 8 {
+		var counts [256]int
+		for _, k := range keys {
+			counts[byte(k>>shift)]++
+		}
+		if counts[byte(keys[0]>>shift)] == length {
+			continue
+		}
+		offset := 0
+		for i, count := range counts {
+			counts[i] = offset
+			offset += count
+		}
+		for i, k := range keys {
+			digit := byte(k >> shift)
+			bufItems[counts[digit]] = items[i]
+			bufKeys[counts[digit]] = k
+			counts[digit]++
+		}
+		items, bufItems = bufItems, items
+		keys, bufKeys = bufKeys, keys
+	}
+	copy(arr, items)
+	return arr
+}
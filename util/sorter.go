@@ -0,0 +1,49 @@
+package util
+
+// Sorter sorts data items in ascending order
+// Implementations may reorder the given array in place and return it
+type Sorter interface {
+	Sort(arr []interface{}, less func(a, b interface{}) bool) []interface{}
+}
+
+// SorterFunc adapts a sorting function to the Sorter interface
+type SorterFunc func(arr []interface{}, less func(a, b interface{}) bool) []interface{}
+
+func (f SorterFunc) Sort(arr []interface{}, less func(a, b interface{}) bool) []interface{} {
+	return f(arr, less)
+}
+
+// Stability may be implemented by a Sorter to tell whether it keeps equal items in their original order
+type Stability interface {
+	Stable() bool
+}
+
+// unstableSorter adapts a sorting function which may reorder equal items
+type unstableSorter SorterFunc
+
+func (f unstableSorter) Sort(arr []interface{}, less func(a, b interface{}) bool) []interface{} {
+	return f(arr, less)
+}
+
+func (f unstableSorter) Stable() bool {
+	return false
+}
+
+var (
+	// HeapSorter sorts in place with heap sort, which is not stable
+	HeapSorter Sorter = unstableSorter(HeapSort)
+	// MergeSorter sorts with a sequential, stable merge sort
+	MergeSorter Sorter = SorterFunc(StableSort)
+	// PdqSorter sorts in place with pattern-defeating quicksort, which is not stable
+	PdqSorter Sorter = unstableSorter(PdqSort)
+	// TimSorter sorts with a stable timsort, which runs in linear time on nearly sorted data
+	TimSorter Sorter = SorterFunc(TimSort)
+)
+
+func insertionSort(arr []interface{}, less func(a, b interface{}) bool) {
+	for i := 1; i < len(arr); i++ {
+		for j := i; j > 0 && less(arr[j], arr[j-1]); j-- {
+			arr[j], arr[j-1] = arr[j-1], arr[j]
+		}
+	}
+}
@@ -0,0 +1,129 @@
+package util
+
+import "sort"
+
+// ItemCount is an estimated number of occurrences of an item
+type ItemCount struct {
+	Item interface{}
+	// Count may overestimate the real number of occurrences, by at most Error
+	Count int
+	Error int
+}
+
+// SpaceSaving tracks the most frequent items of a sequence with at most k counters
+// Any item occurring more than n/k times in n items is guaranteed to be tracked
+type SpaceSaving struct {
+	k        int
+	counters map[interface{}]*counter
+	// next orders counters by creation, so that ties are broken the same way on every run
+	next int
+}
+
+type counter struct {
+	ItemCount
+	order int
+}
+
+// NewSpaceSaving returns an empty summary with k counters
+func NewSpaceSaving(k int) *SpaceSaving {
+	if k < 1 {
+		panic("Number of counters should be greater than 0")
+	}
+	return &SpaceSaving{
+		k:        k,
+		counters: make(map[interface{}]*counter, k),
+	}
+}
+
+// Add counts an occurrence of an item, which should be comparable
+// When all counters are taken, the least counted item is replaced and its count inherited as error
+// Among items counted the same, the one tracked first is replaced
+func (s *SpaceSaving) Add(item interface{}) {
+	if c, ok := s.counters[item]; ok {
+		c.Count++
+		return
+	}
+	if len(s.counters) < s.k {
+		s.track(ItemCount{Item: item, Count: 1})
+		return
+	}
+	min := s.minimum()
+	delete(s.counters, min.Item)
+	s.track(ItemCount{Item: item, Count: min.Count + 1, Error: min.Count})
+}
+
+// Merge adds the counters of another summary into this one, keeping the k largest
+// An item tracked by only one summary may have occurred up to the least count of the other one,
+// which is added to both its count and its error, so that counts keep bounding the real number of occurrences
+func (s *SpaceSaving) Merge(other *SpaceSaving) {
+	mine, theirs := s.floor(), other.floor()
+	for item, c := range s.counters {
+		if _, ok := other.counters[item]; !ok {
+			c.Count += theirs
+			c.Error += theirs
+		}
+	}
+	for _, c := range other.ordered() {
+		if m, ok := s.counters[c.Item]; ok {
+			m.Count += c.Count
+			m.Error += c.Error
+		} else {
+			s.track(ItemCount{Item: c.Item, Count: c.Count + mine, Error: c.Error + mine})
+		}
+	}
+	for len(s.counters) > s.k {
+		delete(s.counters, s.minimum().Item)
+	}
+}
+
+// Top returns the tracked items from the most to the least counted
+func (s *SpaceSaving) Top() []ItemCount {
+	ordered := s.ordered()
+	sort.SliceStable(ordered, func(i, j int) bool {
+		if ordered[i].Count != ordered[j].Count {
+			return ordered[i].Count > ordered[j].Count
+		}
+		return ordered[i].Error < ordered[j].Error
+	})
+	result := make([]ItemCount, len(ordered))
+	for i, c := range ordered {
+		result[i] = c.ItemCount
+	}
+	return result
+}
+
+func (s *SpaceSaving) track(count ItemCount) {
+	s.counters[count.Item] = &counter{ItemCount: count, order: s.next}
+	s.next++
+}
+
+// floor is the most an untracked item may have occurred, which is zero until all counters are taken
+func (s *SpaceSaving) floor() int {
+	if len(s.counters) < s.k {
+		return 0
+	}
+	return s.minimum().Count
+}
+
+// minimum finds the least counted item, the one tracked first among ties
+func (s *SpaceSaving) minimum() *counter {
+	var min *counter
+	for _, c := range s.counters {
+		if min == nil || c.Count < min.Count || c.Count == min.Count && c.order < min.order {
+			min = c
+		}
+	}
+	return min
+}
+
+// ordered lists the counters in the order they were created
+func (s *SpaceSaving) ordered() []*counter {
+	result := make([]*counter, 0, len(s.counters))
+	for _, c := range s.counters {
+		result = append(result, c)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].order < result[j].order
+	})
+	return result
+}
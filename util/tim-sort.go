@@ -0,0 +1,138 @@
+package util
+
+// TimSort sorts data items with timsort, keeping the order of equal items
+// It detects runs of already ordered items, which makes it run in linear time on nearly sorted data
+func TimSort(arr []interface{}, less func(a, b interface{}) bool) []interface{} {
+	length := len(arr)
+	if length < 2 {
+		return arr
+	}
+	sorter := timSorter{
+		arr:  arr,
+		less: less,
+	}
+	minRun := timMinRun(length)
+	for lo := 0; lo < length; {
+		run := sorter.countRun(lo)
+		if run < minRun {
+			forced := minRun
+			if forced > length-lo {
+				forced = length - lo
+			}
+			sorter.binaryInsertionSort(lo, lo+forced, lo+run)
+			run = forced
+		}
+		sorter.runs = append(sorter.runs, timRun{start: lo, length: run})
+		sorter.mergeCollapse()
+		lo += run
+	}
+	for len(sorter.runs) > 1 {
+		n := len(sorter.runs) - 2
+		if n > 0 && sorter.runs[n-1].length < sorter.runs[n+1].length {
+			n--
+		}
+		sorter.mergeAt(n)
+	}
+	return arr
+}
+
+type timRun struct {
+	start  int
+	length int
+}
+
+type timSorter struct {
+	arr  []interface{}
+	less func(a, b interface{}) bool
+	runs []timRun
+	buf  []interface{}
+}
+
+// timMinRun returns the minimal run length so that the number of runs is a power of 2, or slightly less
+func timMinRun(length int) int {
+	r := 0
+	for length >= 32 {
+		r |= length & 1
+		length >>= 1
+	}
+	return length + r
+}
+
+// countRun returns the length of the run starting at lo, reversing it if strictly descending
+func (s *timSorter) countRun(lo int) int {
+	hi := lo + 1
+	if hi == len(s.arr) {
+		return 1
+	}
+	if s.less(s.arr[hi], s.arr[lo]) {
+		for hi++; hi < len(s.arr) && s.less(s.arr[hi], s.arr[hi-1]); hi++ {
+		}
+		for i, j := lo, hi-1; i < j; i, j = i+1, j-1 {
+			s.arr[i], s.arr[j] = s.arr[j], s.arr[i]
+		}
+	} else {
+		for hi++; hi < len(s.arr) && !s.less(s.arr[hi], s.arr[hi-1]); hi++ {
+		}
+	}
+	return hi - lo
+}
+
+// binaryInsertionSort sorts [lo, hi) knowing that [lo, start) is already sorted
+func (s *timSorter) binaryInsertionSort(lo, hi, start int) {
+	for i := start; i < hi; i++ {
+		pivot := s.arr[i]
+		left, right := lo, i
+		for left < right {
+			mid := int(uint(left+right) >> 1)
+			if s.less(pivot, s.arr[mid]) {
+				right = mid
+			} else {
+				left = mid + 1
+			}
+		}
+		copy(s.arr[left+1:i+1], s.arr[left:i])
+		s.arr[left] = pivot
+	}
+}
+
+// mergeCollapse merges pending runs until their lengths decrease faster than the Fibonacci sequence
+func (s *timSorter) mergeCollapse() {
+	for len(s.runs) > 1 {
+		n := len(s.runs) - 2
+		if n > 0 && s.runs[n-1].length <= s.runs[n].length+s.runs[n+1].length ||
+			n > 1 && s.runs[n-2].length <= s.runs[n-1].length+s.runs[n].length {
+			if s.runs[n-1].length < s.runs[n+1].length {
+				n--
+			}
+		} else if s.runs[n].length > s.runs[n+1].length {
+			break
+		}
+		s.mergeAt(n)
+	}
+}
+
+// mergeAt merges the runs at n and n+1
+func (s *timSorter) mergeAt(n int) {
+	left, right := s.runs[n], s.runs[n+1]
+	s.runs[n].length += right.length
+	s.runs = append(s.runs[:n+1], s.runs[n+2:]...)
+
+	if cap(s.buf) < left.length {
+		s.buf = make([]interface{}, left.length)
+	}
+	buf := s.buf[:left.length]
+	copy(buf, s.arr[left.start:right.start])
+	i, j, k := 0, right.start, left.start
+	end := right.start + right.length
+	for i < len(buf) && j < end {
+		if s.less(s.arr[j], buf[i]) {
+			s.arr[k] = s.arr[j]
+			j++
+		} else {
+			s.arr[k] = buf[i]
+			i++
+		}
+		k++
+	}
+	copy(s.arr[k:], buf[i:])
+}